@@ -5,3 +5,11 @@ go 1.23.6
 require github.com/google/uuid v1.6.0
 
 require github.com/lib/pq v1.10.9
+
+require github.com/mattn/go-sqlite3 v1.14.32
+
+require golang.org/x/crypto v0.35.0
+
+require golang.org/x/term v0.29.0
+
+require golang.org/x/sys v0.30.0 // indirect
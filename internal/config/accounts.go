@@ -0,0 +1,225 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"rss-aggregator/internal/database"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+const defaultSessionExpiry = 30 * 24 * time.Hour
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassword prompts for a password without echoing it. When stdin is not
+// a terminal the password is read as a line, so scripts can pipe it in.
+func readPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		fmt.Println()
+		return string(password), err
+	}
+	line, err := stdin.ReadString('\n')
+	fmt.Println()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptNewPassword asks for a password twice. An empty password is only
+// accepted when allowEmpty is set and means no password.
+func promptNewPassword(allowEmpty bool) (string, error) {
+	prompt := "New password: "
+	if allowEmpty {
+		prompt = "Password (empty for none): "
+	}
+	password, err := readPassword(prompt)
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		if allowEmpty {
+			return "", nil
+		}
+		return "", errors.New("password must not be empty")
+	}
+	repeated, err := readPassword("Repeat password: ")
+	if err != nil {
+		return "", err
+	}
+	if repeated != password {
+		return "", errors.New("passwords do not match")
+	}
+	return password, nil
+}
+
+func hashPassword(password string) (sql.NullString, error) {
+	if password == "" {
+		return sql.NullString{}, nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+// checkPassword prompts for the password of the user, users without one
+// pass right away.
+func checkPassword(user database.User, prompt string) error {
+	if !user.PasswordHash.Valid {
+		return nil
+	}
+	password, err := readPassword(prompt)
+	if err != nil {
+		return err
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password))
+	if err != nil {
+		return errors.New("wrong password")
+	}
+	return nil
+}
+
+// startSession logs the user in, replacing the session in the config file.
+func startSession(ctx context.Context, s *State, user database.User, expiry time.Duration) error {
+	now := time.Now()
+	if s.Config.Session != nil {
+		err := s.Db.DeleteSession(ctx, hashToken(s.Config.Session.Token))
+		if err != nil {
+			return err
+		}
+	}
+	err := s.Db.DeleteExpiredSessions(ctx, now)
+	if err != nil {
+		return err
+	}
+	token, hash, err := newToken(sessionTokenPrefix)
+	if err != nil {
+		return err
+	}
+	params := database.CreateSessionParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(expiry),
+	}
+	err = s.Db.CreateSession(ctx, params)
+	if err != nil {
+		return err
+	}
+	s.Config.SetSession(Session{Token: token, ExpiresAt: params.ExpiresAt})
+	return nil
+}
+
+// loggedInUser is the user of the session in the config file. Config files
+// from before sessions name the user instead, which is only trusted for
+// users without a password.
+func loggedInUser(ctx context.Context, s *State) (database.User, error) {
+	if s.Config.Session != nil {
+		params := database.GetUserBySessionParams{TokenHash: hashToken(s.Config.Session.Token), Now: time.Now()}
+		user, err := s.Db.GetUserBySession(ctx, params)
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, errors.New("session expired or ended, log in again")
+		}
+		return user, err
+	}
+	if s.Config.Username == "" {
+		return database.User{}, errors.New("not logged in, use login or register")
+	}
+	user, err := s.Db.GetUser(ctx, s.Config.Username)
+	if err != nil {
+		return user, fmt.Errorf("user may not exist. %w", err)
+	}
+	if user.PasswordHash.Valid {
+		return user, fmt.Errorf("%s has a password, log in with: login %s", user.Name, user.Name)
+	}
+	return user, nil
+}
+
+// currentUserName is the name of the logged in user, empty when nobody is.
+func currentUserName(s *State) string {
+	user, err := loggedInUser(context.Background(), s)
+	if err != nil {
+		return ""
+	}
+	return user.Name
+}
+
+// HandlerPasswd sets or changes the password of the logged in user. Other
+// sessions of the user end and an enabled Fever key follows the password.
+func HandlerPasswd(s *State, cmd CommandInput, user database.User) error {
+	fs := flag.NewFlagSet("passwd", flag.ContinueOnError)
+	remove := fs.Bool("remove", false, "remove the password, the user can then log in by name only")
+	_, err := parseFlags(fs, cmd.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	if *remove && !user.PasswordHash.Valid {
+		fmt.Printf("%s has no password\n", user.Name)
+		return nil
+	}
+	err = checkPassword(user, "Current password: ")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	hash, password := sql.NullString{}, ""
+	if !*remove {
+		password, err = promptNewPassword(false)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		hash, err = hashPassword(password)
+		if err != nil {
+			fmt.Printf("Error hashing password. %s\n", err)
+			os.Exit(1)
+		}
+	}
+	ctx := context.Background()
+	params := database.SetUserPasswordParams{ID: user.ID, PasswordHash: hash, UpdatedAt: time.Now()}
+	err = s.Db.SetUserPassword(ctx, params)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	err = s.Db.DeleteSessionsForUser(ctx, user.ID)
+	if err == nil {
+		s.Config.Session = nil
+		err = startSession(ctx, s, user, defaultSessionExpiry)
+	}
+	if err != nil {
+		fmt.Printf("Error renewing the session. %s\n", err)
+		os.Exit(1)
+	}
+	fever, err := renewFeverKey(ctx, s, user, password)
+	if err != nil {
+		fmt.Printf("Error renewing the Fever key. %s\n", err)
+		os.Exit(1)
+	}
+	if fever && *remove {
+		fmt.Println("Fever API disabled, it needs a password")
+	}
+	if *remove {
+		fmt.Printf("Password of %s removed\n", user.Name)
+		return nil
+	}
+	fmt.Printf("Password of %s changed\n", user.Name)
+	return nil
+}
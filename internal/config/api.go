@@ -0,0 +1,465 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"rss-aggregator/internal/database"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// api serves the users, feeds, follows and posts of the database as JSON.
+// Records have the same fields as the --output json of the CLI. Every
+// request needs a bearer token and acts as the user the token belongs to.
+type api struct {
+	s *State
+}
+
+func newAPI(s *State) *api {
+	return &api{s: s}
+}
+
+func (a *api) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users", a.withUser(a.listUsers))
+	mux.HandleFunc("POST /users", a.withUser(a.createUser))
+	mux.HandleFunc("GET /users/{name}", a.withUser(a.getUser))
+	mux.HandleFunc("GET /follows", a.withUser(a.listFollows))
+	mux.HandleFunc("POST /follows", a.withUser(a.createFollow))
+	mux.HandleFunc("DELETE /follows/{feedID}", a.withUser(a.deleteFollow))
+	mux.HandleFunc("GET /posts", a.withUser(a.listPosts))
+	mux.HandleFunc("GET /feeds", a.withUser(a.listFeeds))
+	mux.HandleFunc("POST /feeds", a.withUser(a.createFeed))
+	mux.HandleFunc("GET /feed", withQueryToken(a.withUser(a.timeline)))
+	// Fever clients authenticate with their own api key
+	mux.HandleFunc("/fever", a.fever)
+	mux.HandleFunc("/fever/", a.fever)
+	return jsonErrors(mux)
+}
+
+// withQueryToken also accepts the token as the token query parameter, for
+// clients that cannot send headers such as most feed readers.
+func withQueryToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next(w, r)
+	}
+}
+
+// withUser authenticates the bearer token of the request and runs the
+// handler as the user the token belongs to, like MiddlewareLoggedIn does for
+// commands.
+func (a *api) withUser(handler func(w http.ResponseWriter, r *http.Request, user database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "bearer token is required")
+			return
+		}
+		now := time.Now()
+		params := database.GetUserByApiTokenParams{
+			TokenHash: hashToken(strings.TrimSpace(token)),
+			Now:       sql.NullTime{Time: now, Valid: true},
+		}
+		row, err := a.s.Db.GetUserByApiToken(r.Context(), params)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "token is invalid, expired or revoked")
+			return
+		}
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		err = a.s.Db.TouchApiToken(r.Context(), database.TouchApiTokenParams{
+			ID:         row.TokenID,
+			LastUsedAt: sql.NullTime{Time: now, Valid: true},
+		})
+		if err != nil {
+			log.Printf("Error recording use of token %s. %s", row.TokenID, err)
+		}
+		handler(w, r, database.User{ID: row.ID, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt, Name: row.Name})
+	}
+}
+
+func (a *api) listUsers(w http.ResponseWriter, r *http.Request, current database.User) {
+	users, err := a.s.Db.GetUsers(r.Context())
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	records := make([]userRecord, len(users))
+	for i, user := range users {
+		records[i] = newUserRecord(user, current.Name)
+	}
+	writeResponse(w, http.StatusOK, records)
+}
+
+func (a *api) getUser(w http.ResponseWriter, r *http.Request, current database.User) {
+	name := r.PathValue("name")
+	user, err := a.s.Db.GetUser(r.Context(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "user %q does not exist", name)
+		return
+	}
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, newUserRecord(user, current.Name))
+}
+
+func (a *api) createUser(w http.ResponseWriter, r *http.Request, current database.User) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	_, err := a.s.Db.GetUser(r.Context(), name)
+	if err == nil {
+		writeError(w, http.StatusConflict, "user %q already exists", name)
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		writeInternalError(w, err)
+		return
+	}
+	user, err := a.s.Db.CreateUser(r.Context(), userParams(name))
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, newUserRecord(user, current.Name))
+}
+
+func (a *api) listFeeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := a.s.Db.GetFeeds(r.Context())
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	records := make([]feedRecord, len(feeds))
+	for i, feed := range feeds {
+		records[i] = newFeedRecord(feed)
+	}
+	writeResponse(w, http.StatusOK, records)
+}
+
+// createFeed adds a feed and follows it, like addfeed.
+func (a *api) createFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Name == "" {
+		writeError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+	if !validFeedURL(body.URL) {
+		writeError(w, http.StatusBadRequest, "url %q is not a valid http(s) url", body.URL)
+		return
+	}
+	_, err := a.s.Db.GetFeed(r.Context(), body.URL)
+	if err == nil {
+		writeError(w, http.StatusConflict, "feed %q already exists", body.URL)
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		writeInternalError(w, err)
+		return
+	}
+	feed, err := a.s.Db.CreateFeed(r.Context(), feedParams(body.Name, body.URL, user.ID))
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	_, err = a.s.Db.CreateFeedFollow(r.Context(), feedFollowParams(user.ID, feed.ID))
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, newFeedRecord(database.GetFeedsRow{
+		ID:            feed.ID,
+		CreatedAt:     feed.CreatedAt,
+		UpdatedAt:     feed.UpdatedAt,
+		Name:          feed.Name,
+		Url:           feed.Url,
+		SiteUrl:       feed.SiteUrl,
+		UserID:        feed.UserID,
+		LastFetchedAt: feed.LastFetchedAt,
+		Username:      user.Name,
+	}))
+}
+
+// userFollow finds the follow of the feed among the follows of the user.
+func (a *api) userFollow(r *http.Request, user database.User, feedID uuid.UUID) (database.GetFeedFollowsForUserRow, bool, error) {
+	follows, err := a.s.Db.GetFeedFollowsForUser(r.Context(), user.Name)
+	if err != nil {
+		return database.GetFeedFollowsForUserRow{}, false, err
+	}
+	for _, follow := range follows {
+		if follow.FeedID == feedID {
+			return follow, true, nil
+		}
+	}
+	return database.GetFeedFollowsForUserRow{}, false, nil
+}
+
+func (a *api) listFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := a.s.Db.GetFeedFollowsForUser(r.Context(), user.Name)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	records := make([]followRecord, len(follows))
+	for i, follow := range follows {
+		records[i] = newFollowRecord(follow)
+	}
+	writeResponse(w, http.StatusOK, records)
+}
+
+// createFollow follows a feed given by feed_id or feed_url.
+func (a *api) createFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		FeedID  string `json:"feed_id"`
+		FeedURL string `json:"feed_url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	var feed database.Feed
+	var err error
+	switch {
+	case body.FeedID != "":
+		id, parseErr := uuid.Parse(body.FeedID)
+		if parseErr != nil {
+			writeError(w, http.StatusBadRequest, "invalid feed_id %q", body.FeedID)
+			return
+		}
+		feed, err = a.s.Db.GetFeedByID(r.Context(), id)
+	case body.FeedURL != "":
+		feed, err = a.s.Db.GetFeed(r.Context(), body.FeedURL)
+	default:
+		writeError(w, http.StatusBadRequest, "feed_id or feed_url is required")
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "feed does not exist")
+		return
+	}
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	_, following, err := a.userFollow(r, user, feed.ID)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	if following {
+		writeError(w, http.StatusConflict, "%s already follows %q", user.Name, feed.Name)
+		return
+	}
+	_, err = a.s.Db.CreateFeedFollow(r.Context(), feedFollowParams(user.ID, feed.ID))
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	follow, _, err := a.userFollow(r, user, feed.ID)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, newFollowRecord(follow))
+}
+
+func (a *api) deleteFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid feed id %q", r.PathValue("feedID"))
+		return
+	}
+	follow, following, err := a.userFollow(r, user, feedID)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	if !following {
+		writeError(w, http.StatusNotFound, "%s does not follow feed %s", user.Name, feedID)
+		return
+	}
+	err = a.s.Db.RemoveFeedFollow(r.Context(), database.RemoveFeedFollowParams{ID: user.ID, Url: follow.FeedUrl})
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type postPage struct {
+	Posts      []postRecord `json:"posts"`
+	NextCursor *string      `json:"next_cursor"`
+}
+
+// listPosts pages through the posts of the followed feeds like browse. The
+// query parameters are feed_id, category, since, until, sort, unread, limit
+// and cursor.
+func (a *api) listPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+	params := database.BrowsePostsByPublishedParams{UserID: user.ID}
+	if value := query.Get("feed_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid feed_id %q", value)
+			return
+		}
+		params.FeedID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if value := query.Get("category"); value != "" {
+		params.Category = sql.NullString{String: strings.Trim(value, categorySeparator), Valid: true}
+	}
+	for name, target := range map[string]*sql.NullTime{"since": &params.Since, "until": &params.Until} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		date, err := parseDateArg(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		*target = sql.NullTime{Time: date, Valid: true}
+	}
+	if value := query.Get("unread"); value != "" {
+		unread, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid unread %q, use true or false", value)
+			return
+		}
+		params.UnreadOnly = unread
+	}
+	sort := sortPublished
+	if value := query.Get("sort"); value != "" {
+		sort = value
+	}
+	if sort != sortPublished && sort != sortFetched {
+		writeError(w, http.StatusBadRequest, "invalid sort %q, use published or fetched", sort)
+		return
+	}
+	limit := defaultPageSize
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeError(w, http.StatusBadRequest, "invalid limit %q, use 1 to %d", value, maxPageSize)
+			return
+		}
+		limit = n
+	}
+	if value := query.Get("cursor"); value != "" {
+		err := applyCursor(&params, value, sort)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+	}
+
+	posts, next, err := browsePage(r.Context(), a.s, sort, params, limit)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	page := postPage{Posts: make([]postRecord, len(posts))}
+	for i, post := range posts {
+		page.Posts[i] = newPostRecord(post)
+	}
+	if next != "" {
+		page.NextCursor = &next
+	}
+	writeResponse(w, http.StatusOK, page)
+}
+
+// timeline serves the newest posts of the followed feeds as an RSS 2.0 or
+// Atom document, so the timeline can be subscribed to from another reader.
+// The query parameters are format, category and limit.
+func (a *api) timeline(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+	opts := timelineOptions{
+		Format:   timelineRSS,
+		Category: strings.Trim(query.Get("category"), categorySeparator),
+		Limit:    defaultTimelineLimit,
+		SelfURL:  requestURL(r),
+	}
+	if value := query.Get("format"); value != "" {
+		opts.Format = value
+	}
+	if opts.Format != timelineRSS && opts.Format != timelineAtom {
+		writeError(w, http.StatusBadRequest, "invalid format %q, use rss or atom", opts.Format)
+		return
+	}
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeError(w, http.StatusBadRequest, "invalid limit %q, use 1 to %d", value, maxPageSize)
+			return
+		}
+		opts.Limit = n
+	}
+	posts, err := timelinePosts(r.Context(), a.s, user, opts.Category, opts.Limit)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	var doc bytes.Buffer
+	err = writeTimeline(&doc, user, opts, posts)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	// ServeContent answers If-Modified-Since, readers polling an unchanged
+	// timeline get a 304
+	w.Header().Set("Content-Type", timelineContentType(opts.Format))
+	http.ServeContent(w, r, "", timelineUpdated(posts), bytes.NewReader(doc.Bytes()))
+}
+
+// requestURL is the absolute url the request was made to, without the
+// credentials some clients send in the query. It ends up in documents that
+// are shared and cached.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawPath: r.URL.RawPath}
+	query := r.URL.Query()
+	for _, name := range queryCredentials {
+		query.Del(name)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
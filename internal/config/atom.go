@@ -0,0 +1,119 @@
+package config
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type AtomFeed struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string       `xml:"id"`
+	Title    AtomText     `xml:"title"`
+	Subtitle AtomText     `xml:"subtitle"`
+	Links    []AtomLink   `xml:"link"`
+	Updated  string       `xml:"updated"`
+	Authors  []AtomPerson `xml:"author"`
+	Entries  []AtomEntry  `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string       `xml:"id"`
+	Title     AtomText     `xml:"title"`
+	Links     []AtomLink   `xml:"link"`
+	Summary   AtomText     `xml:"summary"`
+	Content   AtomText     `xml:"content"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Authors   []AtomPerson `xml:"author"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+// AtomText holds a text construct. Plain text and html content arrive as
+// character data, xhtml content is a nested <div> and is kept as markup.
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+func (feed *AtomFeed) normalize() *ParsedFeed {
+	feedAuthor := atomAuthor(feed.Authors)
+	items := []FeedItem{}
+	for _, entry := range feed.Entries {
+		author := atomAuthor(entry.Authors)
+		if author == "" {
+			author = feedAuthor
+		}
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+		items = append(items, FeedItem{
+			ID:          entry.ID,
+			Title:       entry.Title.String(),
+			Link:        alternateLink(entry.Links),
+			Description: entry.Summary.String(),
+			Content:     entry.Content.String(),
+			PubDate:     published,
+			Updated:     entry.Updated,
+			Author:      author,
+		})
+	}
+	return &ParsedFeed{
+		Title:       feed.Title.String(),
+		Link:        alternateLink(feed.Links),
+		Description: feed.Subtitle.String(),
+		Items:       items,
+	}
+}
+
+// alternateLink picks the link a reader would open in a browser. A missing
+// rel attribute means "alternate" per RFC 4287, html links win over others.
+func alternateLink(links []AtomLink) string {
+	found := ""
+	for _, link := range links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+		if link.Type == "" || link.Type == "text/html" {
+			return link.Href
+		}
+		if found == "" {
+			found = link.Href
+		}
+	}
+	return found
+}
+
+func atomAuthor(authors []AtomPerson) string {
+	names := []string{}
+	for _, author := range authors {
+		name := strings.TrimSpace(author.Name)
+		if name == "" {
+			name = strings.TrimSpace(author.Email)
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"rss-aggregator/internal/database"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	sortPublished = "published"
+	sortFetched   = "fetched"
+)
+
+// browsePost is a row of either browse query, both return the same columns.
+type browsePost = database.BrowsePostsByPublishedRow
+
+// browseCursor points behind the last post of a page. Pages are read with
+// keyset pagination on (sort date, id), so a cursor only fits the sort order
+// it was created for.
+type browseCursor struct {
+	Sort string
+	Time time.Time
+	ID   uuid.UUID
+}
+
+func (c browseCursor) String() string {
+	raw := strings.Join([]string{c.Sort, c.Time.Format(time.RFC3339Nano), c.ID.String()}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseBrowseCursor(value string) (browseCursor, error) {
+	invalid := fmt.Errorf("invalid cursor %q", value)
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return browseCursor{}, invalid
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return browseCursor{}, invalid
+	}
+	cursor := browseCursor{Sort: parts[0]}
+	cursor.Time, err = time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return browseCursor{}, invalid
+	}
+	cursor.ID, err = uuid.Parse(parts[2])
+	if err != nil {
+		return browseCursor{}, invalid
+	}
+	return cursor, nil
+}
+
+func sortTime(post browsePost, sort string) time.Time {
+	if sort == sortFetched {
+		return post.CreatedAt
+	}
+	return post.PublishedAt.Time
+}
+
+func HandlerBrowse(s *State, cmd CommandInput, user database.User) error {
+	fs := flag.NewFlagSet("browse", flag.ContinueOnError)
+	limit := fs.Int("limit", 2, "number of posts to show")
+	feedURL := fs.String("feed", "", "only show posts of the feed with this url")
+	category := fs.String("category", "", "only show posts of feeds in this category or its subcategories")
+	since := fs.String("since", "", "only show posts published on or after this date")
+	until := fs.String("until", "", "only show posts published before this date")
+	sort := fs.String("sort", sortPublished, "order posts by their published or fetched date: published|fetched")
+	cursor := fs.String("cursor", "", "continue after the last post of a previous page")
+	unread := fs.Bool("unread", false, "only show posts that have not been read")
+	templateName := fs.String("template", "", "render posts with a named template (compact, full, markdown or one from the config file) or inline template text")
+	output := outputFlag(fs, s)
+	args, err := parseFlags(fs, cmd.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	s.Output = validOutput(*output)
+	if len(args) > 0 {
+		fmt.Printf("Unexpected argument %q, browse only takes flags (e.g. --limit 5)\n", args[0])
+		os.Exit(1)
+	}
+	if *limit < 1 {
+		fmt.Println("Limit must be at least 1")
+		os.Exit(1)
+	}
+	if *sort != sortPublished && *sort != sortFetched {
+		fmt.Printf("Invalid sort %q, use published or fetched\n", *sort)
+		os.Exit(1)
+	}
+	if *templateName != "" && s.Output != outputText {
+		fmt.Println("--template only applies to --output text")
+		os.Exit(1)
+	}
+	name := *templateName
+	if name == "" {
+		name = defaultBrowseTemplate
+	}
+	tmpl, err := browseTemplate(s.Config, name)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	params := database.BrowsePostsByPublishedParams{
+		UserID:     user.ID,
+		Category:   sql.NullString{String: strings.Trim(*category, categorySeparator), Valid: *category != ""},
+		Since:      dateFlag(*since),
+		Until:      dateFlag(*until),
+		UnreadOnly: *unread,
+	}
+	if *feedURL != "" {
+		feed, err := s.Db.GetFeed(context.Background(), *feedURL)
+		if err != nil {
+			fmt.Printf("Error. Feed may not exist. %s\n", err)
+			os.Exit(1)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if *cursor != "" {
+		err = applyCursor(&params, *cursor, *sort)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	posts, next, err := browsePage(context.Background(), s, *sort, params, *limit)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	if s.Output != outputText {
+		records := make([]postRecord, len(posts))
+		for i, post := range posts {
+			records[i] = newPostRecord(post)
+		}
+		printListing(s, records, postListing)
+		if next != "" {
+			// stderr keeps the output parseable
+			fmt.Fprintf(os.Stderr, "More posts: --cursor %s\n", next)
+		}
+		return nil
+	}
+	if *templateName != "" {
+		// only the rendered posts go to stdout, so they can be saved as is
+		printPosts(tmpl, posts)
+		if next != "" {
+			fmt.Fprintf(os.Stderr, "More posts: --cursor %s\n", next)
+		}
+		return nil
+	}
+	fmt.Printf("\nLatest posts for %s:\n\n", user.Name)
+	printPosts(tmpl, posts)
+	if next != "" {
+		fmt.Printf("More posts: --cursor %s\n", next)
+	}
+	return nil
+}
+
+// applyCursor makes the query continue after the post the cursor points to.
+func applyCursor(params *database.BrowsePostsByPublishedParams, value string, sort string) error {
+	after, err := parseBrowseCursor(value)
+	if err != nil {
+		return err
+	}
+	if after.Sort != sort {
+		return fmt.Errorf("the cursor belongs to sort %s", after.Sort)
+	}
+	params.AfterTime = sql.NullTime{Time: after.Time, Valid: true}
+	params.AfterID = uuid.NullUUID{UUID: after.ID, Valid: true}
+	return nil
+}
+
+// browsePage reads up to limit posts and the cursor of the page after them,
+// which is empty on the last page.
+func browsePage(ctx context.Context, s *State, sort string, params database.BrowsePostsByPublishedParams, limit int) ([]browsePost, string, error) {
+	params.MaxResults = int32(limit) + 1 // one more to know whether there is a next page
+	posts, err := browsePosts(ctx, s, sort, params)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(posts) <= limit {
+		return posts, "", nil
+	}
+	posts = posts[:limit]
+	last := posts[len(posts)-1]
+	return posts, browseCursor{Sort: sort, Time: sortTime(last, sort), ID: last.ID}.String(), nil
+}
+
+func browsePosts(ctx context.Context, s *State, sort string, params database.BrowsePostsByPublishedParams) ([]browsePost, error) {
+	if sort == sortPublished {
+		return s.Db.BrowsePostsByPublished(ctx, params)
+	}
+	rows, err := s.Db.BrowsePostsByFetched(ctx, database.BrowsePostsByFetchedParams(params))
+	if err != nil {
+		return nil, err
+	}
+	posts := make([]browsePost, len(rows))
+	for i, row := range rows {
+		posts[i] = browsePost(row)
+	}
+	return posts, nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseBrowseCursor(t *testing.T) {
+	id := uuid.MustParse("6f1c2a0e-3b4d-4e5f-8a9b-0c1d2e3f4a5b")
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+	tests := []struct {
+		name    string
+		value   string
+		want    browseCursor
+		wantErr bool
+	}{
+		{
+			name:  "published",
+			value: browseCursor{Sort: sortPublished, Time: time.Date(2024, 3, 1, 9, 30, 0, 123456789, time.UTC), ID: id}.String(),
+			want:  browseCursor{Sort: sortPublished, Time: time.Date(2024, 3, 1, 9, 30, 0, 123456789, time.UTC), ID: id},
+		},
+		{
+			name:  "fetched with offset",
+			value: encode("fetched|2024-03-01T10:30:00+01:00|" + id.String()),
+			want:  browseCursor{Sort: sortFetched, Time: time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC), ID: id},
+		},
+		{name: "empty", value: "", wantErr: true},
+		{name: "not base64", value: "not a cursor!", wantErr: true},
+		{name: "padded base64", value: base64.URLEncoding.EncodeToString([]byte("published|2024-03-01T09:30:00Z|" + id.String())), wantErr: true},
+		{name: "missing id", value: encode("published|2024-03-01T09:30:00Z"), wantErr: true},
+		{name: "extra part", value: encode("published|2024-03-01T09:30:00Z|" + id.String() + "|x"), wantErr: true},
+		{name: "bad time", value: encode("published|yesterday|" + id.String()), wantErr: true},
+		{name: "bad id", value: encode("published|2024-03-01T09:30:00Z|42"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBrowseCursor(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBrowseCursor(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got.Sort != tt.want.Sort || !got.Time.Equal(tt.want.Time) || got.ID != tt.want.ID {
+				t.Errorf("parseBrowseCursor(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,10 +3,15 @@ package config
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
 	"rss-aggregator/internal/database"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,27 +22,32 @@ type CommandInput struct {
 	Args []string
 }
 type State struct {
-	Db     *database.Queries
-	Config *Config
+	Db       database.Store
+	Config   *Config
+	Migrator *database.Migrator
+	Output   string
 }
 
 type Commands struct {
 	Map map[string]func(*State, CommandInput) error
 }
 
-func CleanArgs(args []string) []string {
-	if len(args) <= 1 { // first is the program name
+// CleanArgs drops the program name and returns the command with its
+// arguments and the output format of the global --output option.
+func CleanArgs(args []string) ([]string, string) {
+	args, output := outputOption(args[1:]) // first is the program name
+	if len(args) == 0 {
 		fmt.Println("Not enough arguments")
 		os.Exit(1)
 	}
-	return args[1:]
+	return args, output
 }
 
 func MiddlewareLoggedIn(handler func(s *State, cmd CommandInput, user database.User) error) func(*State, CommandInput) error {
 	return func(s *State, cmd CommandInput) error {
-		user, err := s.Db.GetUser(context.Background(), s.Config.Username)
+		user, err := loggedInUser(context.Background(), s)
 		if err != nil {
-			fmt.Printf("Error. User may not exist. %s\n", err)
+			fmt.Printf("Error. %s\n", err)
 			os.Exit(1)
 		}
 		return handler(s, cmd, user)
@@ -45,17 +55,37 @@ func MiddlewareLoggedIn(handler func(s *State, cmd CommandInput, user database.U
 }
 
 func HandlerLogin(s *State, cmd CommandInput) error {
-	if len(cmd.Args) == 1 {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	expires := fs.String("expires", "30d", "how long the session lasts, e.g. 30d or 12h")
+	args, err := parseFlags(fs, cmd.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	if len(args) == 0 {
 		fmt.Println("Username is required")
 		os.Exit(1)
 	}
-	username := cmd.Args[1]
-	user, err := s.Db.GetUser(context.Background(), username)
+	expiry, err := parseExpiry(*expires)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	user, err := s.Db.GetUser(context.Background(), args[0])
 	if err != nil {
 		fmt.Println("User must exist to be logged in")
 		os.Exit(1)
 	}
-	s.Config.SetUser(user.Name)
+	err = checkPassword(user, "Password: ")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	err = startSession(context.Background(), s, user, expiry)
+	if err != nil {
+		fmt.Printf("Error starting session. %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("User has been set to: %s\n", user.Name)
 	return nil
 }
 
@@ -64,13 +94,29 @@ func HandlerRegister(s *State, cmd CommandInput) error {
 		fmt.Println("User name is required")
 		os.Exit(1)
 	}
-	user, err := s.Db.CreateUser(context.Background(), userParams(cmd.Args[1]))
+	password, err := promptNewPassword(true)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	params := userParams(cmd.Args[1])
+	params.PasswordHash, err = hashPassword(password)
+	if err != nil {
+		fmt.Printf("Error hashing password. %s\n", err)
+		os.Exit(1)
+	}
+	user, err := s.Db.CreateUser(context.Background(), params)
 	if err != nil {
 		fmt.Printf("Error. User with name may already exist. %s\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("User has been registered:\n User:\t%v\n", user)
-	s.Config.SetUser(user.Name)
+	fmt.Printf("User has been registered:\n User:\t%s (%s)\n", user.Name, user.ID)
+	err = startSession(context.Background(), s, user, defaultSessionExpiry)
+	if err != nil {
+		fmt.Printf("Error starting session. %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("User has been set to: %s\n", user.Name)
 	return nil
 }
 
@@ -91,14 +137,24 @@ func HandlerReset(s *State, cmd CommandInput) error {
 }
 
 func HandlerListUsers(s *State, cmd CommandInput) error {
+	listingFlags(s, "users", cmd.Args[1:])
 	users, err := s.Db.GetUsers(context.Background())
 	if err != nil {
 		fmt.Printf("Could not retrieve users. %s\n", err)
 		os.Exit(1)
 	}
+	current := currentUserName(s)
+	if s.Output != outputText {
+		records := make([]userRecord, len(users))
+		for i, user := range users {
+			records[i] = newUserRecord(user, current)
+		}
+		printListing(s, records, userListing)
+		return nil
+	}
 	for _, user := range users {
 		user_msg := fmt.Sprintf("* %v", user.Name)
-		if user.Name == s.Config.Username {
+		if user.Name == current {
 			user_msg = user_msg + " (current)"
 		}
 		fmt.Println(user_msg)
@@ -107,11 +163,20 @@ func HandlerListUsers(s *State, cmd CommandInput) error {
 }
 
 func HandlerListFeeds(s *State, cmd CommandInput) error {
+	listingFlags(s, "feeds", cmd.Args[1:])
 	feeds, err := s.Db.GetFeeds(context.Background())
 	if err != nil {
 		fmt.Printf("Could not retrieve feeds. %s\n", err)
 		os.Exit(1)
 	}
+	if s.Output != outputText {
+		records := make([]feedRecord, len(feeds))
+		for i, feed := range feeds {
+			records[i] = newFeedRecord(feed)
+		}
+		printListing(s, records, feedListing)
+		return nil
+	}
 
 	for _, feed := range feeds {
 		fmt.Printf("* %s,%s,%s\n", feed.Name, feed.Url, feed.Username)
@@ -120,11 +185,22 @@ func HandlerListFeeds(s *State, cmd CommandInput) error {
 }
 
 func HandlerAgg(s *State, cmd CommandInput) error {
-	if len(cmd.Args) == 1 {
+	fs := flag.NewFlagSet("agg", flag.ContinueOnError)
+	concurrency := fs.Int("concurrency", 1, "number of feeds fetched in parallel")
+	batchSize := fs.Int("batch", 0, "number of feeds claimed per tick (default: concurrency)")
+	minInterval := fs.Duration("min-interval", 0, "shortest time between two fetches of a feed (default: refresh interval)")
+	maxInterval := fs.Duration("max-interval", 24*time.Hour, "longest time between two fetches of a feed")
+	maxFailures := fs.Int("max-failures", 10, "consecutive failures after which a feed is disabled (0 never disables)")
+	pruneInterval := fs.Duration("prune-interval", 0, "prune posts according to the retention settings this often (0 never prunes)")
+	args, err := parseFlags(fs, cmd.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	if len(args) == 0 {
 		fmt.Println("Refresh interval is required")
 		os.Exit(1)
 	}
-	refreshInterval, err := time.ParseDuration(cmd.Args[1])
+	refreshInterval, err := time.ParseDuration(args[0])
 	if err != nil {
 		fmt.Println("Invalid refresh interval")
 		os.Exit(1)
@@ -133,12 +209,115 @@ func HandlerAgg(s *State, cmd CommandInput) error {
 		fmt.Println("Too short refresh interval")
 		os.Exit(1)
 	}
-	fmt.Printf("Collecting feeds every %s\n", cmd.Args[1])
+	if *concurrency < 1 {
+		fmt.Println("Concurrency must be at least 1")
+		os.Exit(1)
+	}
+	if *batchSize < 1 {
+		*batchSize = *concurrency
+	}
+	if *minInterval <= 0 {
+		*minInterval = refreshInterval
+	}
+	if *maxInterval < *minInterval {
+		fmt.Println("Max interval must not be shorter than min interval")
+		os.Exit(1)
+	}
+	fmt.Printf("Collecting up to %d feeds every %s with %d workers\n", *batchSize, args[0], *concurrency)
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	ticker := time.NewTicker(refreshInterval)
-	for ; ; <-ticker.C {
-		ScrapeFeeds(s, cmd)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+	for {
+		ScrapeFeeds(ctx, s, scrapeOptions{
+			Workers:     *concurrency,
+			BatchSize:   *batchSize,
+			Bounds:      scheduleBounds{Min: *minInterval, Max: *maxInterval},
+			MaxFailures: *maxFailures,
+		})
+		if *pruneInterval > 0 && time.Since(lastPrune) >= *pruneInterval && ctx.Err() == nil {
+			_, err := pruneFeeds(ctx, s, false)
+			if err != nil {
+				fmt.Printf("Error pruning posts: %v\n", err)
+			}
+			lastPrune = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Println("Aggregator stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func HandlerFeedStatus(s *State, cmd CommandInput) error {
+	fs := flag.NewFlagSet("feedstatus", flag.ContinueOnError)
+	enable := fs.String("enable", "", "url of a disabled feed to fetch again")
+	events := fs.Int("events", 0, "show the given number of most recent feed events")
+	_, err := parseFlags(fs, cmd.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	if *enable != "" {
+		feed, err := s.Db.GetFeed(context.Background(), *enable)
+		if err != nil {
+			fmt.Printf("Error. Feed may not exist. %s\n", err)
+			os.Exit(1)
+		}
+		err = s.Db.EnableFeed(context.Background(), feed.ID)
+		if err != nil {
+			fmt.Printf("Error %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Feed %s has been enabled\n", feed.Name)
+		return nil
+	}
+	if *events > 0 {
+		feedEvents, err := s.Db.GetFeedEvents(context.Background(), int32(*events))
+		if err != nil {
+			fmt.Printf("Could not retrieve feed events. %s\n", err)
+			os.Exit(1)
+		}
+		for _, event := range feedEvents {
+			fmt.Printf("* %s %-6s %s %s\n", event.CreatedAt.Format(time.DateTime), event.Kind, event.FeedUrl, event.Detail.String)
+		}
+		return nil
+	}
+	feeds, err := s.Db.GetUnhealthyFeeds(context.Background())
+	if err != nil {
+		fmt.Printf("Could not retrieve feeds. %s\n", err)
+		os.Exit(1)
+	}
+	if len(feeds) == 0 {
+		fmt.Println("All feeds are healthy")
+		return nil
+	}
+	for _, feed := range feeds {
+		state := "failing"
+		if feed.DisabledAt.Valid {
+			state = "disabled since " + feed.DisabledAt.Time.Format(time.DateTime)
+			if feed.LastStatus.Int32 == http.StatusGone {
+				state = "gone since " + feed.DisabledAt.Time.Format(time.DateTime)
+			}
+		}
+		status := "-"
+		if feed.LastStatus.Valid {
+			status = strconv.Itoa(int(feed.LastStatus.Int32))
+		}
+		lastSuccess := "never"
+		if feed.LastSuccessAt.Valid {
+			lastSuccess = feed.LastSuccessAt.Time.Format(time.DateTime)
+		}
+		fmt.Printf("* %s (%s) %s\n", feed.Name, feed.Url, state)
+		fmt.Printf("  failures: %d, last status: %s, last success: %s\n", feed.ConsecutiveFailures, status, lastSuccess)
+		if feed.LastError.Valid {
+			fmt.Printf("  last error: %s\n", strings.TrimSpace(feed.LastError.String))
+		}
 	}
+	return nil
 }
 
 func HandlerAddFeed(s *State, cmd CommandInput, user database.User) error {
@@ -185,11 +364,6 @@ func HandlerUnfollow(s *State, cmd CommandInput, user database.User) error {
 		fmt.Println("Feed name is required")
 		os.Exit(1)
 	}
-	user, err := s.Db.GetUser(context.Background(), s.Config.Username)
-	if err != nil {
-		fmt.Printf("Error. User may not exist. %s\n", err)
-		os.Exit(1)
-	}
 	feed, err := s.Db.GetFeed(context.Background(), cmd.Args[1])
 	if err != nil {
 		fmt.Printf("Error. Feed may not exist. %s\n", err)
@@ -206,39 +380,27 @@ func HandlerUnfollow(s *State, cmd CommandInput, user database.User) error {
 }
 
 func HandlerFollowing(s *State, cmd CommandInput, user database.User) error {
+	listingFlags(s, "following", cmd.Args[1:])
 	feed_follows, err := s.Db.GetFeedFollowsForUser(context.Background(), user.Name)
 	if err != nil {
 		fmt.Printf("Error %s\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("%s follows:\n", user.Name)
-	for _, feedFollow := range feed_follows {
-		fmt.Printf("* %s\n", feedFollow.FeedName)
-	}
-	return nil
-}
-
-func HandlerBrowse(s *State, cmd CommandInput, user database.User) error {
-	postLimit := 2
-	if len(cmd.Args) >= 2 {
-		num, err := strconv.Atoi(cmd.Args[1])
-		if err == nil {
-			postLimit = num
+	if s.Output != outputText {
+		records := make([]followRecord, len(feed_follows))
+		for i, feedFollow := range feed_follows {
+			records[i] = newFollowRecord(feedFollow)
 		}
+		printListing(s, records, followListing)
+		return nil
 	}
-	params := database.GetPostsForUserParams{UserID: user.ID, Limit: int32(postLimit)}
-	posts, err := s.Db.GetPostsForUser(context.Background(), params)
-	if err != nil {
-		fmt.Printf("Error %s\n", err)
-		os.Exit(1)
-	}
-	fmt.Printf("\nLatest posts for %s:\n\n", user.Name)
-	for _, post := range posts {
-		desc := "Empty"
-		if post.Description.Valid {
-			desc = post.Description.String
+	fmt.Printf("%s follows:\n", user.Name)
+	for _, feedFollow := range feed_follows {
+		if feedFollow.UnreadCount > 0 {
+			fmt.Printf("* %s (%d unread)\n", feedFollow.FeedName, feedFollow.UnreadCount)
+			continue
 		}
-		fmt.Printf("#########\n%s\n#########\n\n%s\n\nContinue: %s\n\n", post.Title, desc, post.Url)
+		fmt.Printf("* %s\n", feedFollow.FeedName)
 	}
 	return nil
 }
@@ -5,13 +5,35 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 )
 
 const configFile = ".gatorconfig.json"
 
 type Config struct {
-	DBurl    string `json:"db_url"`
-	Username string `json:"username"`
+	DBurl string `json:"db_url"`
+	// Username is only read from config files written before sessions, it
+	// is dropped on the next login.
+	Username  string     `json:"username,omitempty"`
+	Session   *Session   `json:"session,omitempty"`
+	Retention *Retention `json:"retention,omitempty"`
+	// Templates are named browse templates, see browse --template.
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// Session is the login of the current user. The database only stores a
+// hash of the token.
+type Session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Retention is the global post retention policy. Feeds can override
+// KeepPosts and KeepDays, zero means no limit.
+type Retention struct {
+	KeepPosts      int `json:"keep_posts,omitempty"`
+	KeepDays       int `json:"keep_days,omitempty"`
+	KeepUnreadDays int `json:"keep_unread_days,omitempty"`
 }
 
 func Read() *Config {
@@ -27,17 +49,31 @@ func Read() *Config {
 	return config
 }
 
-func (c *Config) SetUser(user string) {
-	c.Username = user
+func (c *Config) SetSession(session Session) {
+	c.Username = ""
+	c.Session = &session
+	c.write()
+}
+
+func (c *Config) SetRetention(retention Retention) {
+	c.Retention = &retention
+	c.write()
+}
+
+func (c *Config) write() {
 	data, err := json.Marshal(c)
 	if err != nil {
 		log.Fatal(err)
 	}
-	err = os.WriteFile(configLocation(), data, 0644)
+	// the session token is a secret
+	err = os.WriteFile(configLocation(), data, 0600)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = os.Chmod(configLocation(), 0600)
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Printf("User has been set to: %s\n", user)
 }
 
 func configLocation() string {
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// isoLayouts cover RFC 3339 and the ISO 8601 shapes Atom and JSON Feed
+// producers emit, e.g. "2024-03-01T09:30:00.123+01:00" or "2024-03-01 09:30".
+var isoLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// rfc822Layouts are tried after the weekday has been stripped and named
+// zones have been replaced by offsets, so "Tue, 10 Jun 2003 04:00:00 GMT",
+// "Thurs, 5 June 2003 9:00 EST" and "10 Jun 03 04:00 +0000" all end up here.
+var rfc822Layouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04 -07:00",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 January 2006 15:04:05",
+	"2 Jan 2006",
+	"2 January 2006",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2 2006 15:04:05",
+	"January 2 2006 15:04:05",
+	"Jan 2 2006",
+	"January 2 2006",
+	"Jan _2 15:04:05 2006",
+	"Jan _2 15:04:05 -0700 2006",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04 -0700",
+}
+
+// namedZones maps the abbreviations found in the wild to fixed offsets.
+// time.Parse would otherwise accept them with a fabricated zero offset.
+var namedZones = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"MET":  "+0100",
+	"CEST": "+0200",
+	"MEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"MSK":  "+0300",
+	"IST":  "+0530",
+	"SGT":  "+0800",
+	"HKT":  "+0800",
+	"JST":  "+0900",
+	"KST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+	"NZST": "+1200",
+	"NZDT": "+1300",
+	"HST":  "-1000",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"AST":  "-0400",
+	"ADT":  "-0300",
+}
+
+var (
+	zoneComment   = regexp.MustCompile(`\s*\([^)]*\)$`)
+	leadingDay    = regexp.MustCompile(`^[A-Za-z]+\.?,?\s+`)
+	offsetSuffix  = regexp.MustCompile(`(?i)\s*(?:GMT|UTC)([+-]\d{1,2})(?::?(\d{2}))?$`)
+	shortOffset   = regexp.MustCompile(`\s([+-])(\d{1,2})$`)
+	weekdayPrefix = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+)
+
+// parseDate turns a feed date into a time. It reports false when nothing
+// matched so the caller can fall back to another value.
+func parseDate(value string) (time.Time, bool) {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range isoLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	value = normalizeRFC822(value)
+	for _, layout := range rfc822Layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseDateArg reads a date given on the command line. Dates without an
+// offset are taken as local time, e.g. "2024-03-01" or "2024-03-01 18:00".
+func parseDateArg(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range isoLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected e.g. 2024-03-01 or 2024-03-01T18:00:00+01:00", value)
+}
+
+func normalizeRFC822(value string) string {
+	value = zoneComment.ReplaceAllString(value, "")
+	if prefix := leadingDay.FindString(value); prefix != "" && isWeekday(prefix) {
+		value = value[len(prefix):]
+	}
+	value = strings.ReplaceAll(value, ",", " ")
+	value = strings.Join(strings.Fields(value), " ")
+	// "GMT+2", "UTC-05:00"
+	if match := offsetSuffix.FindStringSubmatch(value); match != nil {
+		hours := match[1]
+		if len(hours) == 2 {
+			hours = hours[:1] + "0" + hours[1:]
+		}
+		minutes := match[2]
+		if minutes == "" {
+			minutes = "00"
+		}
+		value = value[:len(value)-len(match[0])] + " " + hours + minutes
+	}
+	// "+2" or "-05" without minutes
+	if match := shortOffset.FindStringSubmatch(value); match != nil {
+		hours := match[2]
+		if len(hours) == 1 {
+			hours = "0" + hours
+		}
+		value = value[:len(value)-len(match[0])] + " " + match[1] + hours + "00"
+	}
+	fields := strings.Fields(value)
+	if len(fields) > 0 {
+		last := strings.ToUpper(fields[len(fields)-1])
+		if offset, ok := namedZones[last]; ok {
+			fields[len(fields)-1] = offset
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+func isWeekday(prefix string) bool {
+	lower := strings.ToLower(prefix)
+	for _, day := range weekdayPrefix {
+		if strings.HasPrefix(lower, day) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"rfc 1123", "Tue, 10 Jun 2003 04:00:00 GMT", utc(2003, 6, 10, 4, 0, 0), true},
+		{"rfc 1123 numeric zone", "Tue, 10 Jun 2003 04:00:00 +0200", utc(2003, 6, 10, 2, 0, 0), true},
+		{"rfc 822 two digit year", "10 Jun 03 04:00 +0000", utc(2003, 6, 10, 4, 0, 0), true},
+		{"rfc 822 without weekday", "10 Jun 2003 04:00:00 -0500", utc(2003, 6, 10, 9, 0, 0), true},
+		{"long weekday and month", "Thurs, 5 June 2003 9:00 EST", utc(2003, 6, 5, 14, 0, 0), true},
+		{"single digit day", "Mon, 3 Mar 2025 08:15:00 GMT", utc(2025, 3, 3, 8, 15, 0), true},
+		{"missing seconds", "Tue, 10 Jun 2003 04:00 GMT", utc(2003, 6, 10, 4, 0, 0), true},
+		{"named zone", "Tue, 10 Jun 2003 04:00:00 PDT", utc(2003, 6, 10, 11, 0, 0), true},
+		{"named zone lower case", "Tue, 10 Jun 2003 04:00:00 cest", utc(2003, 6, 10, 2, 0, 0), true},
+		{"offset with colon", "Tue, 10 Jun 2003 04:00:00 +05:30", utc(2003, 6, 9, 22, 30, 0), true},
+		{"gmt plus hours", "Tue, 10 Jun 2003 04:00:00 GMT+2", utc(2003, 6, 10, 2, 0, 0), true},
+		{"utc minus hours and minutes", "Tue, 10 Jun 2003 04:00:00 UTC-05:00", utc(2003, 6, 10, 9, 0, 0), true},
+		{"short offset", "Tue, 10 Jun 2003 04:00:00 -5", utc(2003, 6, 10, 9, 0, 0), true},
+		{"zone comment", "Tue, 10 Jun 2003 04:00:00 +0000 (UTC)", utc(2003, 6, 10, 4, 0, 0), true},
+		{"no zone", "10 Jun 2003 04:00:00", utc(2003, 6, 10, 4, 0, 0), true},
+		{"extra whitespace", "  Tue,  10 Jun 2003\n04:00:00 GMT ", utc(2003, 6, 10, 4, 0, 0), true},
+		{"rfc 3339", "2024-03-01T09:30:00Z", utc(2024, 3, 1, 9, 30, 0), true},
+		{"rfc 3339 with offset", "2024-03-01T09:30:00+01:00", utc(2024, 3, 1, 8, 30, 0), true},
+		{"rfc 3339 fraction", "2024-03-01T09:30:00.123+01:00", time.Date(2024, 3, 1, 8, 30, 0, 123000000, time.UTC), true},
+		{"iso without seconds", "2024-03-01T09:30+01:00", utc(2024, 3, 1, 8, 30, 0), true},
+		{"iso with space", "2024-03-01 09:30", utc(2024, 3, 1, 9, 30, 0), true},
+		{"date only", "2024-03-01", utc(2024, 3, 1, 0, 0, 0), true},
+		{"ansi c", "Mon Jun 10 04:00:00 2003", utc(2003, 6, 10, 4, 0, 0), true},
+		{"empty", "", time.Time{}, false},
+		{"blank", "   ", time.Time{}, false},
+		{"unparseable", "yesterday", time.Time{}, false},
+		{"unknown zone", "Tue, 10 Jun 2003 04:00:00 XYZT", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseDate(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("parseDate(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseDate(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+			if ok && got.Location() != time.UTC {
+				t.Errorf("parseDate(%q) is in %v, want UTC", tt.value, got.Location())
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"rss-aggregator/internal/database"
+	"strings"
+	"time"
+)
+
+const (
+	faviconMaxAge   = 7 * 24 * time.Hour
+	faviconMaxBytes = 64 << 10
+	faviconTimeout  = 10 * time.Second
+)
+
+// refreshFavicon looks up the icon of the site of a feed once a week. A
+// failed lookup is stored as an empty icon so it waits a week as well.
+func refreshFavicon(ctx context.Context, s *State, feed database.Feed, siteURL string) {
+	icon, err := s.Db.GetFeedIcon(ctx, feed.ID)
+	if err == nil && time.Since(icon.FetchedAt) < faviconMaxAge {
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		fmt.Printf("Error reading favicon of %s: %v\n", feed.Url, err)
+		return
+	}
+	mimeType, data, err := fetchFavicon(ctx, siteURL)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("No favicon for %s: %v\n", feed.Name, err)
+		mimeType, data = "", []byte{}
+	}
+	params := database.UpsertFeedIconParams{
+		FeedID:    feed.ID,
+		MimeType:  mimeType,
+		Data:      data,
+		FetchedAt: time.Now(),
+	}
+	err = s.Db.UpsertFeedIcon(ctx, params)
+	if err != nil {
+		fmt.Printf("Error saving favicon: %v\n", err)
+	}
+}
+
+// fetchFavicon downloads /favicon.ico of the host of siteURL. The type is
+// sniffed from the content, servers often send icons as text/plain or
+// answer with an html error page.
+func fetchFavicon(ctx context.Context, siteURL string) (string, []byte, error) {
+	site, err := url.Parse(siteURL)
+	if err != nil || site.Host == "" {
+		return "", nil, fmt.Errorf("invalid site url %q", siteURL)
+	}
+	iconURL := url.URL{Scheme: site.Scheme, Host: site.Host, Path: "/favicon.ico"}
+	ctx, cancel := context.WithTimeout(ctx, faviconTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", iconURL.String(), nil)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("user-agent", "rss-aggregator")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("%s answered with status %d", iconURL.String(), res.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, faviconMaxBytes+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(data) > faviconMaxBytes {
+		return "", nil, fmt.Errorf("icon is larger than %d bytes", faviconMaxBytes)
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", nil, fmt.Errorf("%s is not an image but %s", iconURL.String(), mimeType)
+	}
+	return mimeType, data, nil
+}
@@ -2,8 +2,9 @@ package config
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
-	"encoding/xml"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"html"
@@ -11,120 +12,451 @@ import (
 	"net/http"
 	"rss-aggregator/internal/database"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Channel struct {
-	Title       string    `xml:"title"`
-	Link        string    `xml:"link"`
-	Description string    `xml:"description"`
-	Item        []RSSItem `xml:"item"`
+	Title           string    `xml:"title"`
+	Links           []string  `xml:"link"`
+	Description     string    `xml:"description"`
+	TTL             string    `xml:"ttl"`
+	UpdatePeriod    string    `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string    `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	Item            []RSSItem `xml:"item"`
 }
 type RSSFeed struct {
 	Channel Channel `xml:"channel"`
 }
 
 type RSSItem struct {
+	Guid        string `xml:"guid"`
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
 	PubDate     string `xml:"pubDate"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// ParsedFeed is the format independent result of FetchFeed. Every supported
+// format is mapped onto it before anything is stored.
+type ParsedFeed struct {
+	Format      string
+	Title       string
+	Link        string
+	Description string
+	Items       []FeedItem
+	// UpdateHint is the polling interval the publisher asks for through
+	// <ttl> or <sy:updatePeriod>, zero when the feed does not say.
+	UpdateHint time.Duration
+}
+
+type FeedItem struct {
+	ID          string
+	Title       string
+	Link        string
+	Description string
+	Content     string
+	PubDate     string
+	Updated     string
+	Author      string
+}
+
+// HTTPError is returned by FetchFeed for unsuccessful responses so callers
+// can record the status code.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("Response failed with status code: %d\nbody: %s\n", e.StatusCode, e.Body)
+}
+
+// CacheValidators are the values of the previous response used to make a
+// conditional request.
+type CacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+const (
+	feedMaxBytes = 10 << 20
+	feedTimeout  = 30 * time.Second
+)
+
+// FetchResult is the outcome of FetchFeed. Feed is nil when the server
+// answered 304 Not Modified.
+type FetchResult struct {
+	Feed          *ParsedFeed
+	StatusCode    int
+	NotModified   bool
+	Validators    CacheValidators
+	CacheLifetime time.Duration
+	// MovedTo is the final url when every redirect on the way was permanent.
+	MovedTo string
+}
+
+func FetchFeed(ctx context.Context, feedURL string, cache CacheValidators) (*FetchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, feedTimeout)
+	defer cancel()
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("user-agent", "rss-aggregator")
-	res, err := http.DefaultClient.Do(req)
+	if cache.ETag != "" {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+	if cache.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cache.LastModified)
+	}
+	redirected, permanent := false, true
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			redirected = true
+			status := req.Response.StatusCode
+			if status != http.StatusMovedPermanently && status != http.StatusPermanentRedirect {
+				permanent = false
+			}
+			return nil
+		},
+	}
+	res, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
-	body, err := io.ReadAll(res.Body)
+	result := &FetchResult{
+		StatusCode:    res.StatusCode,
+		Validators:    responseValidators(res, cache),
+		CacheLifetime: cacheLifetime(res.Header, time.Now()),
+	}
+	if redirected && permanent && res.Request.URL.String() != feedURL {
+		result.MovedTo = res.Request.URL.String()
+	}
+	if res.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		return result, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, feedMaxBytes+1))
 	if err != nil {
 		return nil, err
 	}
 	if res.StatusCode > 299 {
-		msg := fmt.Sprintf("Response failed with status code: %d\nbody: %s\n", res.StatusCode, body)
-		err = errors.New(msg)
-		return nil, err
+		return nil, &HTTPError{StatusCode: res.StatusCode, Body: string(body)}
+	}
+	if len(body) > feedMaxBytes {
+		return nil, fmt.Errorf("feed is larger than %d bytes", feedMaxBytes)
 	}
-	var result RSSFeed
-	err = xml.Unmarshal(body, &result)
+	feed, err := parseFeed(res.Header.Get("Content-Type"), body)
 	if err != nil {
 		return nil, err
 	}
-	return cleanResult(&result), nil
+	result.Feed = cleanResult(feed)
+	return result, nil
+}
+
+// responseValidators keeps the previous values when a 304 response omits
+// them, which RFC 9110 allows.
+func responseValidators(res *http.Response, previous CacheValidators) CacheValidators {
+	validators := CacheValidators{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}
+	if res.StatusCode == http.StatusNotModified {
+		if validators.ETag == "" {
+			validators.ETag = previous.ETag
+		}
+		if validators.LastModified == "" {
+			validators.LastModified = previous.LastModified
+		}
+	}
+	return validators
 }
 
-func cleanResult(feed *RSSFeed) *RSSFeed {
-	items := []RSSItem{}
+func (feed *RSSFeed) normalize() *ParsedFeed {
+	items := []FeedItem{}
 	for _, item := range feed.Channel.Item {
-		items = append(items, RSSItem{
-			Title:       html.UnescapeString(item.Title),
+		author := item.Author
+		if author == "" {
+			author = item.Creator
+		}
+		items = append(items, FeedItem{
+			ID:          item.Guid,
+			Title:       item.Title,
 			Link:        item.Link,
-			Description: html.UnescapeString(item.Description),
+			Description: item.Description,
+			Content:     item.Content,
 			PubDate:     item.PubDate,
+			Author:      author,
 		})
 	}
-	cleaned := RSSFeed{
-		Channel: Channel{
-			Title:       html.UnescapeString(feed.Channel.Title),
-			Link:        feed.Channel.Link,
-			Description: html.UnescapeString(feed.Channel.Description),
-			Item:        items,
-		},
+	return &ParsedFeed{
+		Title:       feed.Channel.Title,
+		Link:        firstNonEmpty(feed.Channel.Links...),
+		Description: feed.Channel.Description,
+		Items:       items,
+		UpdateHint:  updateHint(feed.Channel.TTL, feed.Channel.UpdatePeriod, feed.Channel.UpdateFrequency),
+	}
+}
+
+// firstNonEmpty skips the empty values left behind by elements like
+// <atom:link rel="self"/> that share a local name with the RSS element.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func cleanResult(feed *ParsedFeed) *ParsedFeed {
+	items := []FeedItem{}
+	for _, item := range feed.Items {
+		items = append(items, FeedItem{
+			ID:          strings.TrimSpace(item.ID),
+			Title:       html.UnescapeString(item.Title),
+			Link:        strings.TrimSpace(item.Link),
+			Description: html.UnescapeString(item.Description),
+			Content:     html.UnescapeString(item.Content),
+			PubDate:     strings.TrimSpace(item.PubDate),
+			Updated:     strings.TrimSpace(item.Updated),
+			Author:      html.UnescapeString(item.Author),
+		})
+	}
+	cleaned := ParsedFeed{
+		Format:      feed.Format,
+		Title:       html.UnescapeString(feed.Title),
+		Link:        strings.TrimSpace(feed.Link),
+		Description: html.UnescapeString(feed.Description),
+		Items:       items,
+		UpdateHint:  feed.UpdateHint,
 	}
 	return &cleaned
 }
 
-func ScrapeFeeds(s *State, cmd CommandInput) error {
-	feed, err := s.Db.GetNextFeedToFetch(context.Background())
+type scrapeOptions struct {
+	Workers     int
+	BatchSize   int
+	Bounds      scheduleBounds
+	MaxFailures int
+}
+
+// ScrapeFeeds claims a batch of feeds whose next_fetch_at is due and fetches
+// them with a bounded pool of workers. Claiming skips rows locked by other
+// agg processes and leases the feeds, so several aggregators can share one
+// database.
+func ScrapeFeeds(ctx context.Context, s *State, opts scrapeOptions) error {
+	now := time.Now()
+	params := database.ClaimFeedsToFetchParams{
+		Now:        sql.NullTime{Time: now, Valid: true},
+		MaxResults: int32(opts.BatchSize),
+		LeaseUntil: sql.NullTime{Time: now.Add(claimLease), Valid: true},
+	}
+	feeds, err := s.Db.ClaimFeedsToFetch(ctx, params)
 	if err != nil {
-		fmt.Printf("Error getting next feed to fetch: %v", err)
+		fmt.Printf("Error getting feeds to fetch: %v\n", err)
 		return nil
 	}
-	now := time.Now()
-	params := database.MarkFeedFetchedParams{
-		ID:            feed.ID,
-		LastFetchedAt: sql.NullTime{Time: now, Valid: true},
+	jobs := make(chan database.Feed)
+	var wg sync.WaitGroup
+	for range min(opts.Workers, len(feeds)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range jobs {
+				scrapeFeed(ctx, s, feed, opts)
+			}
+		}()
 	}
-	s.Db.MarkFeedFetched(context.Background(), params)
-	rss_feed, err := FetchFeed(context.Background(), feed.Url)
+	for _, feed := range feeds {
+		select {
+		case jobs <- feed:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return nil
+}
+
+func scrapeFeed(ctx context.Context, s *State, feed database.Feed, opts scrapeOptions) {
+	if ctx.Err() != nil {
+		return
+	}
+	cache := CacheValidators{ETag: feed.Etag.String, LastModified: feed.LastModified.String}
+	result, err := FetchFeed(ctx, feed.Url, cache)
 	if err != nil {
-		fmt.Printf("Error fetching feed: %v", err)
-		return nil
+		if ctx.Err() != nil {
+			return // shutting down, the lease expires on its own
+		}
+		fmt.Printf("Error fetching feed %s: %v\n", feed.Url, err)
+		recordFailure(ctx, s, feed, err, opts)
+		return
 	}
-	fmt.Printf("Save new posts from : %s\n", rss_feed.Channel.Title)
-	for _, item := range rss_feed.Channel.Item {
-		post, err := s.Db.CreatePost(context.Background(), postParams(&item, feed.ID))
+	recordSuccess(ctx, s, feed, result.StatusCode)
+	if result.MovedTo != "" {
+		feed, err = relocateFeed(ctx, s, feed, result.MovedTo)
 		if err != nil {
-			if strings.Contains(err.Error(), `duplicate key value violates unique constraint "posts_url_key"`) {
-				continue
-			} else {
-				fmt.Printf("Error saving post to db %v\n", err)
-			}
-		} else {
+			fmt.Printf("Error moving feed %s to %s: %v\n", feed.Url, result.MovedTo, err)
+		}
+	}
+	hints := []time.Duration{result.CacheLifetime}
+	defer func() {
+		publishTimes := recentPublishTimes(context.WithoutCancel(ctx), s, feed)
+		scheduleFeed(ctx, s, feed, nextFetchInterval(hints, publishTimes, opts.Bounds))
+	}()
+	if result.NotModified {
+		fmt.Printf("Not modified: %s\n", feed.Name)
+		saveCacheHeaders(ctx, s, feed, cache, result.Validators)
+		return
+	}
+	rss_feed := result.Feed
+	hints = append(hints, rss_feed.UpdateHint)
+	if !feed.Format.Valid || feed.Format.String != rss_feed.Format {
+		formatParams := database.SetFeedFormatParams{
+			ID:     feed.ID,
+			Format: sql.NullString{String: rss_feed.Format, Valid: true},
+		}
+		err = s.Db.SetFeedFormat(ctx, formatParams)
+		if err != nil {
+			fmt.Printf("Error saving feed format: %v\n", err)
+		}
+	}
+	if rss_feed.Link != "" && feed.SiteUrl.String != rss_feed.Link {
+		siteParams := database.SetFeedSiteUrlParams{
+			ID:      feed.ID,
+			SiteUrl: sql.NullString{String: rss_feed.Link, Valid: true},
+		}
+		err = s.Db.SetFeedSiteUrl(ctx, siteParams)
+		if err != nil {
+			fmt.Printf("Error saving feed site url: %v\n", err)
+		}
+	}
+	refreshFavicon(ctx, s, feed, firstNonEmpty(rss_feed.Link, feed.SiteUrl.String, feed.Url))
+	fmt.Printf("Save new posts from : %s\n", rss_feed.Title)
+	cutoff := scrapeCutoff(ctx, s, feed, time.Now())
+	saved := true
+	for _, item := range rss_feed.Items {
+		params := postParams(&item, feed.ID)
+		if params.PublishedAt.Time.Before(cutoff) {
+			continue // older than what retention keeps
+		}
+		post, err := s.Db.UpsertPost(ctx, params)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // already stored and unchanged
+		}
+		if err != nil {
+			fmt.Printf("Error saving post to db %v\n", err)
+			saved = false
+		} else if post.ID == params.ID {
 			fmt.Printf("* Added post %s\n", post.Title)
+		} else if post.Changed {
+			fmt.Printf("* Updated post %s\n", post.Title)
 		}
 	}
-	return nil
+	// A later 304 would hide the posts that failed to save, so the
+	// validators are only kept once every item is stored.
+	if saved {
+		saveCacheHeaders(ctx, s, feed, cache, result.Validators)
+	}
+}
+
+func saveCacheHeaders(ctx context.Context, s *State, feed database.Feed, old, validators CacheValidators) {
+	if validators == old {
+		return
+	}
+	params := database.SetFeedCacheHeadersParams{
+		ID:           feed.ID,
+		Etag:         sql.NullString{String: validators.ETag, Valid: validators.ETag != ""},
+		LastModified: sql.NullString{String: validators.LastModified, Valid: validators.LastModified != ""},
+	}
+	err := s.Db.SetFeedCacheHeaders(ctx, params)
+	if err != nil {
+		fmt.Printf("Error saving cache headers: %v\n", err)
+	}
+}
+
+func scheduleFeed(ctx context.Context, s *State, feed database.Feed, interval time.Duration) {
+	params := database.SetFeedNextFetchParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: time.Now().Add(interval), Valid: true},
+	}
+	err := s.Db.SetFeedNextFetch(context.WithoutCancel(ctx), params)
+	if err != nil {
+		fmt.Printf("Error scheduling feed %s: %v\n", feed.Url, err)
+	}
+}
+
+func recentPublishTimes(ctx context.Context, s *State, feed database.Feed) []time.Time {
+	params := database.GetRecentPublishTimesParams{FeedID: feed.ID, Limit: recentPostsForSchedule}
+	rows, err := s.Db.GetRecentPublishTimes(ctx, params)
+	if err != nil {
+		fmt.Printf("Error reading publish times of %s: %v\n", feed.Url, err)
+		return nil
+	}
+	times := []time.Time{}
+	for _, row := range rows {
+		times = append(times, row.Time)
+	}
+	return times
 }
 
-func postParams(item *RSSItem, feedID uuid.UUID) database.CreatePostParams {
+func postParams(item *FeedItem, feedID uuid.UUID) database.UpsertPostParams {
 	now := time.Now()
-	return database.CreatePostParams{
+	description := item.Description
+	if description == "" {
+		description = item.Content
+	}
+	publishedAt, ok := parseDate(item.PubDate)
+	if !ok {
+		publishedAt, ok = parseDate(item.Updated)
+	}
+	if !ok {
+		// equal to created_at, which keeps it out of the fetch schedule
+		publishedAt = now
+	}
+	contentHash := itemHash(item)
+	return database.UpsertPostParams{
 		ID:          uuid.New(),
 		CreatedAt:   now,
 		UpdatedAt:   now,
 		Title:       item.Title,
 		Url:         item.Link,
-		Description: sql.NullString{String: item.Description, Valid: true},
-		PublishedAt: sql.NullTime{Time: now, Valid: false}, // handle published at!
+		Description: sql.NullString{String: description, Valid: description != ""},
+		PublishedAt: sql.NullTime{Time: publishedAt.In(now.Location()), Valid: true},
 		FeedID:      feedID,
+		Guid:        itemKey(item, contentHash),
+		ContentHash: sql.NullString{String: contentHash, Valid: true},
+	}
+}
+
+// itemKey identifies an item within its feed: the guid or Atom id when the
+// feed provides one, otherwise the link and as a last resort the content.
+func itemKey(item *FeedItem, contentHash string) string {
+	if item.ID != "" {
+		return item.ID
+	}
+	if item.Link != "" {
+		return item.Link
+	}
+	return "sha256:" + contentHash
+}
+
+func itemHash(item *FeedItem) string {
+	hash := sha256.New()
+	for _, part := range []string{item.Title, item.Link, item.Description, item.Content} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
 	}
+	return hex.EncodeToString(hash.Sum(nil))
 }
@@ -0,0 +1,489 @@
+package config
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"os"
+	"rss-aggregator/internal/database"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	feverAPIVersion = 3
+	feverPageSize   = 50
+	// feverKindling is the group of all feeds in Fever
+	feverKindling = 0
+)
+
+// feverKey is the api key Fever clients derive from the credentials, the
+// md5 of "email:password". The user name takes the place of the email.
+func feverKey(name, password string) string {
+	sum := md5.Sum([]byte(name + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// HandlerFever enables or disables the Fever API for the logged in user.
+// Only a hash of the key is stored, but since the key is an unsalted md5 of
+// the password the API is off until a user turns it on.
+func HandlerFever(s *State, cmd CommandInput, user database.User) error {
+	if len(cmd.Args) == 1 {
+		fmt.Println("Fever command is required: enable or disable")
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	switch cmd.Args[1] {
+	case "enable":
+		if !user.PasswordHash.Valid {
+			fmt.Printf("%s has no password, the Fever key is derived from it. Set one with passwd\n", user.Name)
+			os.Exit(1)
+		}
+		password, err := readPassword("Password: ")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password))
+		if err != nil {
+			fmt.Println("wrong password")
+			os.Exit(1)
+		}
+		err = setFeverKey(ctx, s, user, password)
+		if err != nil {
+			fmt.Printf("Error %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Fever API enabled for %s. Sign in with %s as email and your password at http://<serve address>/fever/\n", user.Name, user.Name)
+		return nil
+	case "disable":
+		removed, err := s.Db.DeleteFeverKey(ctx, user.ID)
+		if err != nil {
+			fmt.Printf("Error %s\n", err)
+			os.Exit(1)
+		}
+		if removed == 0 {
+			fmt.Printf("Fever API is not enabled for %s\n", user.Name)
+			return nil
+		}
+		fmt.Printf("Fever API disabled for %s\n", user.Name)
+		return nil
+	}
+	fmt.Printf("Unknown fever command %q\n", cmd.Args[1])
+	os.Exit(1)
+	return nil
+}
+
+func setFeverKey(ctx context.Context, s *State, user database.User, password string) error {
+	params := database.SetFeverKeyParams{
+		UserID:    user.ID,
+		KeyHash:   hashToken(feverKey(user.Name, password)),
+		CreatedAt: time.Now(),
+	}
+	return s.Db.SetFeverKey(ctx, params)
+}
+
+// renewFeverKey follows a password change, the key of a user who enabled
+// the Fever API is derived from the new password. Without a password the API
+// is disabled. It reports whether the user had the API enabled.
+func renewFeverKey(ctx context.Context, s *State, user database.User, password string) (bool, error) {
+	removed, err := s.Db.DeleteFeverKey(ctx, user.ID)
+	if err != nil || removed == 0 || password == "" {
+		return removed > 0, err
+	}
+	return true, setFeverKey(ctx, s, user, password)
+}
+
+// feverGroupID turns a category into the integer Fever identifies groups by.
+// Categories have no id of their own, the checksum stays the same as long as
+// the name does.
+func feverGroupID(category string) int64 {
+	return int64(crc32.ChecksumIEEE([]byte(category)) & 0x7fffffff)
+}
+
+type feverGroup struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+type feverFeed struct {
+	ID                int32  `json:"id"`
+	FaviconID         int32  `json:"favicon_id"`
+	Title             string `json:"title"`
+	URL               string `json:"url"`
+	SiteURL           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int32  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	HTML          string `json:"html"`
+	URL           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+type feverFavicon struct {
+	ID   int32  `json:"id"`
+	Data string `json:"data"`
+}
+
+// feverRow is a row of any of the item queries, they return the same
+// columns.
+type feverRow = database.GetFeverItemsAfterRow
+
+func newFeverItem(row feverRow) feverItem {
+	created := row.CreatedAt
+	if row.PublishedAt.Valid {
+		created = row.PublishedAt.Time
+	}
+	return feverItem{
+		ID:            row.FeverID,
+		FeedID:        row.FeverFeedID,
+		Title:         row.Title,
+		Author:        row.FeedName,
+		HTML:          row.Description.String,
+		URL:           row.Url,
+		IsSaved:       feverBool(row.SavedAt.Valid),
+		IsRead:        feverBool(row.ReadAt.Valid),
+		CreatedOnTime: created.Unix(),
+	}
+}
+
+func feverBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// fever implements the Fever API that mobile readers such as Reeder sync
+// with. Requests go to /fever/?api with the api_key form field; the query
+// names what to return (groups, feeds, favicons, items, links,
+// unread_item_ids, saved_item_ids) and the mark, as, id and before fields
+// change read and saved states. Unknown ids are ignored.
+func (a *api) fever(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	err := r.ParseForm()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form. %s", err)
+		return
+	}
+	if !r.Form.Has("api") {
+		writeError(w, http.StatusBadRequest, "the Fever API is served at %s?api", r.URL.Path)
+		return
+	}
+	response := map[string]any{"api_version": feverAPIVersion, "auth": 0}
+	user, err := a.s.Db.GetUserByFeverKey(r.Context(), hashToken(strings.ToLower(r.Form.Get("api_key"))))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeResponse(w, http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	response["auth"] = 1
+
+	feeds, err := a.s.Db.GetFeverFeeds(r.Context(), user.ID)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	var lastRefreshed time.Time
+	for _, feed := range feeds {
+		if feed.LastSuccessAt.Valid && feed.LastSuccessAt.Time.After(lastRefreshed) {
+			lastRefreshed = feed.LastSuccessAt.Time
+		}
+	}
+	response["last_refreshed_on_time"] = max(lastRefreshed.Unix(), 0)
+
+	if r.Form.Has("mark") && !a.feverMark(w, r, user, feeds, response) {
+		return
+	}
+	if r.Form.Has("groups") || r.Form.Has("feeds") {
+		groups, feedsGroups := feverGroups(feeds)
+		if r.Form.Has("groups") {
+			response["groups"] = groups
+		}
+		if r.Form.Has("feeds") {
+			response["feeds"] = feverFeeds(feeds)
+		}
+		response["feeds_groups"] = feedsGroups
+	}
+	if r.Form.Has("favicons") {
+		icons, err := a.s.Db.GetFeverFavicons(r.Context(), user.ID)
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		favicons := make([]feverFavicon, len(icons))
+		for i, icon := range icons {
+			favicons[i] = feverFavicon{ID: icon.FeverID, Data: icon.MimeType + ";base64," + base64.StdEncoding.EncodeToString(icon.Data)}
+		}
+		response["favicons"] = favicons
+	}
+	if r.Form.Has("items") && !a.feverItems(w, r, user, response) {
+		return
+	}
+	if r.Form.Has("links") {
+		response["links"] = []any{}
+	}
+	if r.Form.Has("unread_item_ids") {
+		err = a.feverUnreadIDs(r, user, response)
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+	}
+	if r.Form.Has("saved_item_ids") {
+		err = a.feverSavedIDs(r, user, response)
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+	}
+	writeResponse(w, http.StatusOK, response)
+}
+
+// feverGroups turns the categories of the follows into groups. Feeds without
+// a category are only in the Kindling, the group of all feeds.
+func feverGroups(feeds []database.GetFeverFeedsRow) ([]feverGroup, []feverFeedsGroup) {
+	members := map[string][]int64{}
+	for _, feed := range feeds {
+		if feed.Category.String != "" {
+			members[feed.Category.String] = append(members[feed.Category.String], int64(feed.FeverID))
+		}
+	}
+	categories := make([]string, 0, len(members))
+	for category := range members {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	groups := make([]feverGroup, len(categories))
+	feedsGroups := make([]feverFeedsGroup, len(categories))
+	for i, category := range categories {
+		groups[i] = feverGroup{ID: feverGroupID(category), Title: category}
+		feedsGroups[i] = feverFeedsGroup{GroupID: groups[i].ID, FeedIDs: joinIDs(members[category])}
+	}
+	return groups, feedsGroups
+}
+
+func feverFeeds(feeds []database.GetFeverFeedsRow) []feverFeed {
+	records := make([]feverFeed, len(feeds))
+	for i, feed := range feeds {
+		records[i] = feverFeed{
+			ID:      feed.FeverID,
+			Title:   feed.Name,
+			URL:     feed.Url,
+			SiteURL: feed.SiteUrl.String,
+		}
+		if feed.IconMimeType.String != "" {
+			records[i].FaviconID = feed.FeverID
+		}
+		if feed.LastSuccessAt.Valid {
+			records[i].LastUpdatedOnTime = feed.LastSuccessAt.Time.Unix()
+		}
+	}
+	return records
+}
+
+// feverItems adds up to 50 items: those listed in with_ids, those before
+// max_id newest first, or else those after since_id oldest first. On failure
+// the error response is written and false returned.
+func (a *api) feverItems(w http.ResponseWriter, r *http.Request, user database.User, response map[string]any) bool {
+	ctx := r.Context()
+	rows := []feverRow{}
+	switch {
+	case r.Form.Get("with_ids") != "":
+		for _, value := range strings.Split(r.Form.Get("with_ids"), ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid with_ids %q", r.Form.Get("with_ids"))
+				return false
+			}
+			if len(rows) == feverPageSize {
+				break
+			}
+			row, err := a.s.Db.GetFeverItem(ctx, database.GetFeverItemParams{UserID: user.ID, ID: id})
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				writeInternalError(w, err)
+				return false
+			}
+			rows = append(rows, feverRow(row))
+		}
+	case r.Form.Get("max_id") != "":
+		maxID, err := strconv.ParseInt(r.Form.Get("max_id"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid max_id %q", r.Form.Get("max_id"))
+			return false
+		}
+		params := database.GetFeverItemsBeforeParams{UserID: user.ID, MaxID: maxID, MaxResults: feverPageSize}
+		before, err := a.s.Db.GetFeverItemsBefore(ctx, params)
+		if err != nil {
+			writeInternalError(w, err)
+			return false
+		}
+		for _, row := range before {
+			rows = append(rows, feverRow(row))
+		}
+	default:
+		var sinceID int64
+		if value := r.Form.Get("since_id"); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid since_id %q", value)
+				return false
+			}
+			sinceID = id
+		}
+		params := database.GetFeverItemsAfterParams{UserID: user.ID, SinceID: sinceID, MaxResults: feverPageSize}
+		after, err := a.s.Db.GetFeverItemsAfter(ctx, params)
+		if err != nil {
+			writeInternalError(w, err)
+			return false
+		}
+		rows = append(rows, after...)
+	}
+	total, err := a.s.Db.CountFeverItems(ctx, user.ID)
+	if err != nil {
+		writeInternalError(w, err)
+		return false
+	}
+	items := make([]feverItem, len(rows))
+	for i, row := range rows {
+		items[i] = newFeverItem(row)
+	}
+	response["items"] = items
+	response["total_items"] = total
+	return true
+}
+
+func (a *api) feverUnreadIDs(r *http.Request, user database.User, response map[string]any) error {
+	ids, err := a.s.Db.GetUnreadFeverItemIDs(r.Context(), user.ID)
+	response["unread_item_ids"] = joinIDs(ids)
+	return err
+}
+
+func (a *api) feverSavedIDs(r *http.Request, user database.User, response map[string]any) error {
+	ids, err := a.s.Db.GetSavedFeverItemIDs(r.Context(), user.ID)
+	response["saved_item_ids"] = joinIDs(ids)
+	return err
+}
+
+// feverMark changes the state of an item (read, unread, saved, unsaved), or
+// marks the items of a feed or group read that were fetched before the unix
+// time in before. Group 0 is the Kindling, all feeds. The changed id list is
+// added to the response. On failure the error response is written and false
+// returned.
+func (a *api) feverMark(w http.ResponseWriter, r *http.Request, user database.User, feeds []database.GetFeverFeedsRow, response map[string]any) bool {
+	ctx := r.Context()
+	mark, as := r.Form.Get("mark"), r.Form.Get("as")
+	id, err := strconv.ParseInt(r.Form.Get("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id %q", r.Form.Get("id"))
+		return false
+	}
+	now := time.Now()
+	switch {
+	case mark == "item":
+		row, err := a.s.Db.GetFeverItem(ctx, database.GetFeverItemParams{UserID: user.ID, ID: id})
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			writeInternalError(w, err)
+			return false
+		}
+		switch as {
+		case "read":
+			err = a.s.Db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: row.ID, ReadAt: now})
+		case "unread":
+			_, err = a.s.Db.MarkPostUnread(ctx, database.MarkPostUnreadParams{UserID: user.ID, PostID: row.ID})
+		case "saved":
+			err = a.s.Db.SavePost(ctx, database.SavePostParams{UserID: user.ID, PostID: row.ID, CreatedAt: now})
+		case "unsaved":
+			_, err = a.s.Db.UnsavePost(ctx, database.UnsavePostParams{UserID: user.ID, PostID: row.ID})
+		default:
+			writeError(w, http.StatusBadRequest, "invalid as %q, use read, unread, saved or unsaved", as)
+			return false
+		}
+		if err != nil {
+			writeInternalError(w, err)
+			return false
+		}
+	case (mark == "feed" || mark == "group") && as == "read":
+		params := database.MarkFeverItemsReadParams{ReadAt: now, UserID: user.ID, FetchedBefore: now}
+		if value := r.Form.Get("before"); value != "" {
+			before, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid before %q", value)
+				return false
+			}
+			params.FetchedBefore = time.Unix(before, 0)
+		}
+		found := mark == "group" && id == feverKindling
+		for _, feed := range feeds {
+			if mark == "feed" && int64(feed.FeverID) == id {
+				params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+				found = true
+			}
+			if mark == "group" && feed.Category.String != "" && feverGroupID(feed.Category.String) == id {
+				params.Category = feed.Category
+				found = true
+			}
+		}
+		if found {
+			_, err = a.s.Db.MarkFeverItemsRead(ctx, params)
+			if err != nil {
+				writeInternalError(w, err)
+				return false
+			}
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "invalid mark %q as %q", mark, as)
+		return false
+	}
+	if as == "saved" || as == "unsaved" {
+		err = a.feverSavedIDs(r, user, response)
+	} else {
+		err = a.feverUnreadIDs(r, user, response)
+	}
+	if err != nil {
+		writeInternalError(w, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// parseFlags parses flags that may appear before, between or after the
+// positional arguments, e.g. `agg 1m --concurrency 4`. The standard flag
+// package stops at the first positional argument.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// dateFlag parses an optional date flag, exiting on invalid input.
+func dateFlag(value string) sql.NullTime {
+	if value == "" {
+		return sql.NullTime{}
+	}
+	date, err := parseDateArg(value)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return sql.NullTime{Time: date, Valid: true}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"rss-aggregator/internal/database"
+	"time"
+	"unicode/utf8"
+)
+
+// maxStoredErrorLength keeps response bodies embedded in errors from
+// bloating the feeds table.
+const maxStoredErrorLength = 500
+
+func recordSuccess(ctx context.Context, s *State, feed database.Feed, status int) {
+	params := database.RecordFeedSuccessParams{
+		ID:            feed.ID,
+		LastStatus:    sql.NullInt32{Int32: int32(status), Valid: true},
+		LastSuccessAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	err := s.Db.RecordFeedSuccess(ctx, params)
+	if err != nil {
+		fmt.Printf("Error recording fetch of %s: %v\n", feed.Url, err)
+	}
+}
+
+// recordFailure stores the error, backs the feed off exponentially and
+// disables it once it failed maxFailures times in a row. Feeds answering
+// 410 Gone are disabled right away.
+func recordFailure(ctx context.Context, s *State, feed database.Feed, fetchErr error, opts scrapeOptions) {
+	message := truncateError(fetchErr.Error())
+	params := database.RecordFeedFailureParams{
+		ID:        feed.ID,
+		LastError: sql.NullString{String: message, Valid: true},
+	}
+	var httpErr *HTTPError
+	if errors.As(fetchErr, &httpErr) {
+		params.LastStatus = sql.NullInt32{Int32: int32(httpErr.StatusCode), Valid: true}
+	}
+	failures, err := s.Db.RecordFeedFailure(ctx, params)
+	if err != nil {
+		fmt.Printf("Error recording failure of %s: %v\n", feed.Url, err)
+		// Still back off, otherwise the feed is due again when its claim
+		// lease runs out.
+		scheduleFeed(ctx, s, feed, backoff(int(feed.ConsecutiveFailures)+1, opts.Bounds))
+		return
+	}
+	if params.LastStatus.Int32 == http.StatusGone {
+		retireFeed(ctx, s, feed)
+		return
+	}
+	if opts.MaxFailures > 0 && int(failures) >= opts.MaxFailures {
+		disableParams := database.DisableFeedParams{
+			ID:         feed.ID,
+			DisabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+		}
+		err = s.Db.DisableFeed(ctx, disableParams)
+		if err != nil {
+			fmt.Printf("Error disabling feed %s: %v\n", feed.Url, err)
+			return
+		}
+		fmt.Printf("Disabled %s after %d failed fetches\n", feed.Url, failures)
+		return
+	}
+	scheduleFeed(ctx, s, feed, backoff(int(failures), opts.Bounds))
+}
+
+// truncateError cuts a message to maxStoredErrorLength bytes without
+// splitting a multi-byte character.
+func truncateError(message string) string {
+	if len(message) <= maxStoredErrorLength {
+		return message
+	}
+	end := maxStoredErrorLength
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end]
+}
+
+// backoff doubles the wait for every consecutive failure, starting at the
+// minimum fetch interval.
+func backoff(failures int, bounds scheduleBounds) time.Duration {
+	wait := bounds.Min
+	for i := 1; i < failures && wait < bounds.Max; i++ {
+		wait *= 2
+	}
+	return min(wait, bounds.Max)
+}
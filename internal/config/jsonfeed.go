@@ -0,0 +1,102 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// JSONFeed follows https://www.jsonfeed.org/version/1.1/ and still accepts
+// the single "author" object of version 1.0.
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Author      *JSONAuthor    `json:"author"`
+	Authors     []JSONAuthor   `json:"authors"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            any          `json:"id"`
+	URL           string       `json:"url"`
+	ExternalURL   string       `json:"external_url"`
+	Title         string       `json:"title"`
+	ContentHTML   string       `json:"content_html"`
+	ContentText   string       `json:"content_text"`
+	Summary       string       `json:"summary"`
+	DatePublished string       `json:"date_published"`
+	DateModified  string       `json:"date_modified"`
+	Author        *JSONAuthor  `json:"author"`
+	Authors       []JSONAuthor `json:"authors"`
+}
+
+type JSONAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+func (feed *JSONFeed) normalize() *ParsedFeed {
+	feedAuthor := jsonAuthor(feed.Author, feed.Authors)
+	items := []FeedItem{}
+	for _, item := range feed.Items {
+		author := jsonAuthor(item.Author, item.Authors)
+		if author == "" {
+			author = feedAuthor
+		}
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+		published := item.DatePublished
+		if published == "" {
+			published = item.DateModified
+		}
+		items = append(items, FeedItem{
+			ID:          jsonItemID(item.ID),
+			Title:       item.Title,
+			Link:        link,
+			Description: item.Summary,
+			Content:     content,
+			PubDate:     published,
+			Updated:     item.DateModified,
+			Author:      author,
+		})
+	}
+	return &ParsedFeed{
+		Title:       feed.Title,
+		Link:        feed.HomePageURL,
+		Description: feed.Description,
+		Items:       items,
+	}
+}
+
+// jsonItemID accepts numeric ids as well, the spec requires strings but
+// plenty of generators emit plain numbers.
+func jsonItemID(id any) string {
+	switch value := id.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+func jsonAuthor(author *JSONAuthor, authors []JSONAuthor) string {
+	if author != nil {
+		authors = append([]JSONAuthor{*author}, authors...)
+	}
+	names := []string{}
+	for _, a := range authors {
+		if a.Name != "" {
+			names = append(names, a.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
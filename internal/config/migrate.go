@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"rss-aggregator/internal/database"
+	"time"
+)
+
+// RequireCurrentSchema stops the program before a handler runs into sqlc
+// column errors on a database that lacks migrations.
+func RequireCurrentSchema(s *State) {
+	pending, err := s.Migrator.Pending(context.Background())
+	if err != nil {
+		fmt.Printf("Could not read the schema version. %s\n", err)
+		os.Exit(1)
+	}
+	if len(pending) == 0 {
+		return
+	}
+	fmt.Printf("The database schema is behind: %d migration(s) pending, starting with %s.\n", len(pending), pending[0].Name)
+	fmt.Println("Run `rss-aggregator migrate up` to update it.")
+	os.Exit(1)
+}
+
+func HandlerMigrate(s *State, cmd CommandInput) error {
+	if len(cmd.Args) == 1 {
+		fmt.Println("Usage: migrate up|down|status|redo")
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	switch cmd.Args[1] {
+	case "up":
+		applied, err := s.Migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("* Applied %s\n", migration.Name)
+		}
+		if err != nil {
+			fmt.Printf("Error %s\n", err)
+			os.Exit(1)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database schema is up to date")
+		}
+	case "down":
+		migration, err := s.Migrator.Down(ctx)
+		exitOnMigrationError(err)
+		fmt.Printf("* Rolled back %s\n", migration.Name)
+	case "redo":
+		migration, err := s.Migrator.Redo(ctx)
+		exitOnMigrationError(err)
+		fmt.Printf("* Redid %s\n", migration.Name)
+	case "status":
+		statuses, err := s.Migrator.Status(ctx)
+		exitOnMigrationError(err)
+		for _, status := range statuses {
+			appliedAt := "Pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Printf("* %-19s %s\n", appliedAt, status.Name)
+		}
+	default:
+		fmt.Printf("Unknown migrate command %q, use up|down|status|redo\n", cmd.Args[1])
+		os.Exit(1)
+	}
+	return nil
+}
+
+func exitOnMigrationError(err error) {
+	if errors.Is(err, database.ErrNoMigrationApplied) {
+		fmt.Println("No migration has been applied yet")
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,343 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"rss-aggregator/internal/database"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OPML covers versions 1.0 and 2.0, which only differ in optional
+// attributes for subscription lists.
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    OPMLHead `xml:"head"`
+	Body    OPMLBody `xml:"body"`
+}
+
+type OPMLHead struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type OPMLBody struct {
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Category string        `xml:"category,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+// Subscription is a feed outline together with the folders it is nested in.
+type Subscription struct {
+	Title    string
+	URL      string
+	SiteURL  string
+	Category string
+}
+
+// categorySeparator joins nested folder names into one category. A
+// separator inside a folder name is escaped with a backslash.
+const categorySeparator = "/"
+
+func joinCategory(folders []string) string {
+	escaped := make([]string, len(folders))
+	for i, folder := range folders {
+		folder = strings.ReplaceAll(folder, `\`, `\\`)
+		escaped[i] = strings.ReplaceAll(folder, categorySeparator, `\`+categorySeparator)
+	}
+	return strings.Join(escaped, categorySeparator)
+}
+
+// splitCategory is the inverse of joinCategory.
+func splitCategory(category string) []string {
+	folders := []string{}
+	folder := strings.Builder{}
+	for i := 0; i < len(category); i++ {
+		switch {
+		case category[i] == '\\' && i+1 < len(category):
+			i++
+			folder.WriteByte(category[i])
+		case strings.HasPrefix(category[i:], categorySeparator):
+			folders = append(folders, folder.String())
+			folder.Reset()
+		default:
+			folder.WriteByte(category[i])
+		}
+	}
+	return append(folders, folder.String())
+}
+
+func parseOPML(data []byte) (*OPML, error) {
+	doc := OPML{}
+	err := xml.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OPML: %w", err)
+	}
+	return &doc, nil
+}
+
+// Subscriptions flattens the outline tree. Outlines with an xmlUrl are feeds,
+// outlines without one are folders whose names become the category of the
+// feeds below them. Feeds outside any folder fall back to the OPML 2.0
+// category attribute.
+func (doc *OPML) Subscriptions() []Subscription {
+	subs := []Subscription{}
+	var walk func(outlines []OPMLOutline, folders []string)
+	walk = func(outlines []OPMLOutline, folders []string) {
+		for _, outline := range outlines {
+			title := firstNonEmpty(outline.Title, outline.Text)
+			if outline.XMLURL == "" && len(outline.Outlines) > 0 {
+				walk(outline.Outlines, append(folders[:len(folders):len(folders)], title))
+				continue
+			}
+			if outline.XMLURL == "" && outline.HTMLURL == "" {
+				continue // an empty folder
+			}
+			category := joinCategory(folders)
+			if category == "" {
+				category = outlineCategory(outline.Category)
+			}
+			subs = append(subs, Subscription{
+				Title:    strings.TrimSpace(title),
+				URL:      strings.TrimSpace(outline.XMLURL),
+				SiteURL:  strings.TrimSpace(outline.HTMLURL),
+				Category: category,
+			})
+			walk(outline.Outlines, folders)
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+	return subs
+}
+
+// outlineCategory picks the first entry of the comma separated category
+// attribute, "/Tech/Go,/Blogs" becomes "Tech/Go".
+func outlineCategory(attr string) string {
+	first, _, _ := strings.Cut(attr, ",")
+	return strings.Trim(strings.TrimSpace(first), categorySeparator)
+}
+
+func validFeedURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// buildOPML turns the follows of a user into an OPML 2.0 document. Categories
+// become nested folders so that importing the document restores them.
+func buildOPML(title string, follows []database.GetFeedFollowsForUserRow) *OPML {
+	sort.SliceStable(follows, func(i, j int) bool {
+		if follows[i].Category.String != follows[j].Category.String {
+			return follows[i].Category.String < follows[j].Category.String
+		}
+		return strings.ToLower(follows[i].FeedName) < strings.ToLower(follows[j].FeedName)
+	})
+	doc := &OPML{
+		Version: "2.0",
+		Head:    OPMLHead{Title: title, DateCreated: time.Now().Format(time.RFC1123Z)},
+	}
+	for _, follow := range follows {
+		outlines := &doc.Body.Outlines
+		if follow.Category.String != "" {
+			for _, folder := range splitCategory(follow.Category.String) {
+				outlines = folderOutlines(outlines, folder)
+			}
+		}
+		*outlines = append(*outlines, OPMLOutline{
+			Text:    follow.FeedName,
+			Title:   follow.FeedName,
+			Type:    "rss",
+			XMLURL:  follow.FeedUrl,
+			HTMLURL: follow.FeedSiteUrl.String,
+		})
+	}
+	return doc
+}
+
+// folderOutlines returns the children of the folder with the given name,
+// appending the folder when it does not exist yet.
+func folderOutlines(outlines *[]OPMLOutline, name string) *[]OPMLOutline {
+	for i := range *outlines {
+		if (*outlines)[i].XMLURL == "" && (*outlines)[i].Text == name {
+			return &(*outlines)[i].Outlines
+		}
+	}
+	*outlines = append(*outlines, OPMLOutline{Text: name})
+	return &(*outlines)[len(*outlines)-1].Outlines
+}
+
+func writeOPML(w io.Writer, doc *OPML) error {
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
+
+type importSummary struct {
+	Created  int
+	Followed int
+	Skipped  int
+	Invalid  int
+}
+
+func HandlerImport(s *State, cmd CommandInput, user database.User) error {
+	if len(cmd.Args) == 1 {
+		fmt.Println("OPML file is required")
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(cmd.Args[1])
+	if err != nil {
+		fmt.Printf("Error reading %s. %s\n", cmd.Args[1], err)
+		os.Exit(1)
+	}
+	doc, err := parseOPML(data)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	follows, err := s.Db.GetFeedFollowsForUser(ctx, user.Name)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	followed := map[uuid.UUID]bool{}
+	for _, follow := range follows {
+		followed[follow.FeedID] = true
+	}
+
+	summary := importSummary{}
+	for _, sub := range doc.Subscriptions() {
+		if !validFeedURL(sub.URL) {
+			fmt.Printf("Invalid: %q has no usable feed url\n", firstNonEmpty(sub.Title, sub.URL))
+			summary.Invalid++
+			continue
+		}
+		feed, created, err := importFeed(ctx, s, sub, user)
+		if err != nil {
+			fmt.Printf("Invalid: %s. %s\n", sub.URL, err)
+			summary.Invalid++
+			continue
+		}
+		if created {
+			summary.Created++
+		}
+		if followed[feed.ID] {
+			fmt.Printf("Skipped: already following %s\n", feed.Url)
+			summary.Skipped++
+			continue
+		}
+		_, err = s.Db.CreateFeedFollow(ctx, feedFollowParams(user.ID, feed.ID))
+		if err != nil {
+			fmt.Printf("Invalid: could not follow %s. %s\n", feed.Url, err)
+			summary.Invalid++
+			continue
+		}
+		followed[feed.ID] = true
+		if sub.Category != "" {
+			params := database.SetFeedFollowCategoryParams{
+				UserID:    user.ID,
+				FeedID:    feed.ID,
+				Category:  sql.NullString{String: sub.Category, Valid: true},
+				UpdatedAt: time.Now(),
+			}
+			err = s.Db.SetFeedFollowCategory(ctx, params)
+			if err != nil {
+				fmt.Printf("Error setting category of %s. %s\n", feed.Url, err)
+			}
+		}
+		fmt.Printf("Followed: %s\n", feed.Name)
+		summary.Followed++
+	}
+	fmt.Printf("\nImport finished: %d created, %d followed, %d skipped, %d invalid\n",
+		summary.Created, summary.Followed, summary.Skipped, summary.Invalid)
+	return nil
+}
+
+func HandlerExport(s *State, cmd CommandInput, user database.User) error {
+	if len(cmd.Args) == 1 {
+		fmt.Println("Export format is required: opml, starred or feed")
+		os.Exit(1)
+	}
+	switch cmd.Args[1] {
+	case "opml":
+		return exportOPML(s, cmd.Args[2:], user)
+	case "starred":
+		return exportStarred(s, cmd.Args[2:], user)
+	case "feed":
+		return exportTimeline(s, cmd.Args[2:], user)
+	}
+	fmt.Printf("Unknown export format %q\n", cmd.Args[1])
+	os.Exit(1)
+	return nil
+}
+
+// exportOPML writes the subscriptions of the user to the file given as first
+// argument, or to stdout.
+func exportOPML(s *State, args []string, user database.User) error {
+	follows, err := s.Db.GetFeedFollowsForUser(context.Background(), user.Name)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	doc := buildOPML(fmt.Sprintf("%s's subscriptions", user.Name), follows)
+	if len(args) == 0 {
+		return writeOPML(os.Stdout, doc)
+	}
+	file, err := os.Create(args[0])
+	if err != nil {
+		fmt.Printf("Error creating %s. %s\n", args[0], err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	err = writeOPML(file, doc)
+	if err != nil {
+		fmt.Printf("Error writing %s. %s\n", args[0], err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d feeds to %s\n", len(follows), args[0])
+	return nil
+}
+
+// importFeed returns the feed stored under the subscription url, creating it
+// when it does not exist yet. The htmlUrl of the outline fills in a missing
+// site url until the feed is fetched.
+func importFeed(ctx context.Context, s *State, sub Subscription, user database.User) (database.Feed, bool, error) {
+	created := false
+	feed, err := s.Db.GetFeed(ctx, sub.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		feed, err = s.Db.CreateFeed(ctx, feedParams(firstNonEmpty(sub.Title, sub.URL), sub.URL, user.ID))
+		created = err == nil
+	}
+	if err != nil {
+		return feed, false, err
+	}
+	if !feed.SiteUrl.Valid && sub.SiteURL != "" {
+		feed.SiteUrl = sql.NullString{String: sub.SiteURL, Valid: true}
+		err = s.Db.SetFeedSiteUrl(ctx, database.SetFeedSiteUrlParams{ID: feed.ID, SiteUrl: feed.SiteUrl})
+		if err != nil {
+			fmt.Printf("Error saving site url of %s. %s\n", feed.Url, err)
+		}
+	}
+	return feed, created, nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"reflect"
+	"rss-aggregator/internal/database"
+	"testing"
+)
+
+func TestOPMLRoundTrip(t *testing.T) {
+	follow := func(name, url, siteURL, category string) database.GetFeedFollowsForUserRow {
+		return database.GetFeedFollowsForUserRow{
+			FeedName:    name,
+			FeedUrl:     url,
+			FeedSiteUrl: sql.NullString{String: siteURL, Valid: siteURL != ""},
+			Category:    sql.NullString{String: category, Valid: category != ""},
+		}
+	}
+	follows := []database.GetFeedFollowsForUserRow{
+		follow("Go blog", "https://go.dev/blog/feed.atom", "https://go.dev/blog", "Tech/Go"),
+		follow("Rust blog", "https://blog.rust-lang.org/feed.xml", "", "Tech"),
+		follow("HN", "https://news.ycombinator.com/rss", "https://news.ycombinator.com/", ""),
+		follow("Slashed", "https://example.com/slash.xml", "", `News\/Tech/Deep`),
+		follow("Backslash", "https://example.com/backslash.xml", "", `C:\\Feeds`),
+		follow("Tom & Jerry <3", "https://example.com/escaped.xml", "", "Fun"),
+	}
+	want := map[string]Subscription{}
+	for _, f := range follows {
+		want[f.FeedUrl] = Subscription{
+			Title:    f.FeedName,
+			URL:      f.FeedUrl,
+			SiteURL:  f.FeedSiteUrl.String,
+			Category: f.Category.String,
+		}
+	}
+
+	buf := bytes.Buffer{}
+	err := writeOPML(&buf, buildOPML("test", follows))
+	if err != nil {
+		t.Fatalf("writeOPML() error = %v", err)
+	}
+	doc, err := parseOPML(buf.Bytes())
+	if err != nil {
+		t.Fatalf("parseOPML() error = %v\n%s", err, buf.String())
+	}
+	got := map[string]Subscription{}
+	for _, sub := range doc.Subscriptions() {
+		got[sub.URL] = sub
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v\n%s", got, want, buf.String())
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<opml version="1.0">
+  <body>
+    <outline text="Empty"/>
+    <outline text="Also empty"></outline>
+    <outline text="Top" xmlUrl=" https://example.com/top.xml " category="/Blogs/Tech,/Other"/>
+    <outline text="News/Tech">
+      <outline title="Deep">
+        <outline text="Nested" xmlUrl="https://example.com/nested.xml" htmlUrl="https://example.com/"/>
+      </outline>
+    </outline>
+    <outline text="No feed url" htmlUrl="https://example.com/page"/>
+  </body>
+</opml>`
+	doc, err := parseOPML([]byte("\xef\xbb\xbf" + body))
+	if err != nil {
+		t.Fatalf("parseOPML() error = %v", err)
+	}
+	want := []Subscription{
+		{Title: "Top", URL: "https://example.com/top.xml", Category: "Blogs/Tech"},
+		{Title: "Nested", URL: "https://example.com/nested.xml", SiteURL: "https://example.com/", Category: `News\/Tech/Deep`},
+		{Title: "No feed url", SiteURL: "https://example.com/page"},
+	}
+	got := doc.Subscriptions()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Subscriptions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSplitCategory(t *testing.T) {
+	tests := []struct {
+		category string
+		want     []string
+	}{
+		{"Tech", []string{"Tech"}},
+		{"Tech/Go", []string{"Tech", "Go"}},
+		{`News\/Tech/Deep`, []string{"News/Tech", "Deep"}},
+		{`C:\\Feeds`, []string{`C:\Feeds`}},
+		{`trailing\`, []string{`trailing\`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			got := splitCategory(tt.category)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCategory(%q) = %q, want %q", tt.category, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"rss-aggregator/internal/database"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+	outputCSV    = "csv"
+)
+
+// outputCommands are the listing commands that take --output.
+var outputCommands = map[string]bool{
+	"users":     true,
+	"feeds":     true,
+	"following": true,
+	"browse":    true,
+}
+
+// outputOption pulls the global --output option out of the arguments before
+// the command name. Later arguments are left to the command, listing commands
+// take --output as a flag of their own and others may contain free text.
+func outputOption(args []string) ([]string, string) {
+	output := ""
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		if name != "output" {
+			break
+		}
+		if !hasValue {
+			if len(args) == 1 {
+				fmt.Println("--output needs a format: text, json, ndjson or csv")
+				os.Exit(1)
+			}
+			value = args[1]
+			args = args[1:]
+		}
+		output = validOutput(value)
+		args = args[1:]
+	}
+	if output == "" {
+		return args, outputText
+	}
+	if len(args) > 0 && !outputCommands[args[0]] {
+		fmt.Printf("%s does not support --output\n", args[0])
+		os.Exit(1)
+	}
+	return args, output
+}
+
+// outputFlag adds --output to the flags of a listing command. It defaults to
+// the global option given before the command name.
+func outputFlag(fs *flag.FlagSet, s *State) *string {
+	return fs.String("output", s.Output, "output format: text|json|ndjson|csv")
+}
+
+// listingFlags parses the arguments of a listing command that has no flags
+// besides --output.
+func listingFlags(s *State, name string, args []string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	output := outputFlag(fs, s)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		os.Exit(1)
+	}
+	if len(args) > 0 {
+		fmt.Printf("Unexpected argument %q, %s only takes --output\n", args[0], name)
+		os.Exit(1)
+	}
+	s.Output = validOutput(*output)
+}
+
+// validOutput exits unless output is a supported format.
+func validOutput(output string) string {
+	switch output {
+	case outputText, outputJSON, outputNDJSON, outputCSV:
+		return output
+	}
+	fmt.Printf("Invalid output format %q, use text, json, ndjson or csv\n", output)
+	os.Exit(1)
+	return ""
+}
+
+// listing describes the csv form of the records of a listing command. json
+// and ndjson use the struct tags of the record type instead.
+type listing[T any] struct {
+	Columns []string
+	Row     func(T) []string
+}
+
+// writeListing writes the records as one json array, as one json object per
+// line or as csv with a header row.
+func writeListing[T any](w io.Writer, format string, records []T, l listing[T]) error {
+	switch format {
+	case outputJSON:
+		if records == nil {
+			records = []T{}
+		}
+		return writeJSON(w, records)
+	case outputNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, record := range records {
+			err := encoder.Encode(record)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputCSV:
+		writer := csv.NewWriter(w)
+		err := writer.Write(l.Columns)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			err = writer.Write(l.Row(record))
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// printListing writes the records to stdout in the format chosen with
+// --output.
+func printListing[T any](s *State, records []T, l listing[T]) {
+	err := writeListing(os.Stdout, s.Output, records, l)
+	if err != nil {
+		fmt.Printf("Error writing output. %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// nullString and nullTime turn missing values into null in json, csv writes
+// them as empty strings. Timestamps are always written in UTC.
+func nullString(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func nullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	utc := value.Time.UTC()
+	return &utc
+}
+
+func csvString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func csvTime(value time.Time) string {
+	return value.UTC().Format(time.RFC3339Nano)
+}
+
+func csvNullTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return csvTime(*value)
+}
+
+type userRecord struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newUserRecord(user database.User, current string) userRecord {
+	return userRecord{
+		ID:        user.ID.String(),
+		Name:      user.Name,
+		Current:   user.Name == current,
+		CreatedAt: user.CreatedAt.UTC(),
+		UpdatedAt: user.UpdatedAt.UTC(),
+	}
+}
+
+var userListing = listing[userRecord]{
+	Columns: []string{"id", "name", "current", "created_at", "updated_at"},
+	Row: func(r userRecord) []string {
+		return []string{r.ID, r.Name, strconv.FormatBool(r.Current), csvTime(r.CreatedAt), csvTime(r.UpdatedAt)}
+	},
+}
+
+type feedRecord struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	SiteURL       *string    `json:"site_url"`
+	UserID        string     `json:"user_id"`
+	UserName      string     `json:"user_name"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+}
+
+func newFeedRecord(feed database.GetFeedsRow) feedRecord {
+	return feedRecord{
+		ID:            feed.ID.String(),
+		Name:          feed.Name,
+		URL:           feed.Url,
+		SiteURL:       nullString(feed.SiteUrl),
+		UserID:        feed.UserID.String(),
+		UserName:      feed.Username,
+		CreatedAt:     feed.CreatedAt.UTC(),
+		UpdatedAt:     feed.UpdatedAt.UTC(),
+		LastFetchedAt: nullTime(feed.LastFetchedAt),
+	}
+}
+
+var feedListing = listing[feedRecord]{
+	Columns: []string{"id", "name", "url", "site_url", "user_id", "user_name", "created_at", "updated_at", "last_fetched_at"},
+	Row: func(r feedRecord) []string {
+		return []string{
+			r.ID, r.Name, r.URL, csvString(r.SiteURL), r.UserID, r.UserName,
+			csvTime(r.CreatedAt), csvTime(r.UpdatedAt), csvNullTime(r.LastFetchedAt),
+		}
+	},
+}
+
+type followRecord struct {
+	ID          string    `json:"id"`
+	FeedID      string    `json:"feed_id"`
+	FeedName    string    `json:"feed_name"`
+	FeedURL     string    `json:"feed_url"`
+	SiteURL     *string   `json:"site_url"`
+	Category    *string   `json:"category"`
+	UnreadCount int64     `json:"unread_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newFollowRecord(follow database.GetFeedFollowsForUserRow) followRecord {
+	return followRecord{
+		ID:          follow.ID.String(),
+		FeedID:      follow.FeedID.String(),
+		FeedName:    follow.FeedName,
+		FeedURL:     follow.FeedUrl,
+		SiteURL:     nullString(follow.FeedSiteUrl),
+		Category:    nullString(follow.Category),
+		UnreadCount: follow.UnreadCount,
+		CreatedAt:   follow.CreatedAt.UTC(),
+		UpdatedAt:   follow.UpdatedAt.UTC(),
+	}
+}
+
+var followListing = listing[followRecord]{
+	Columns: []string{"id", "feed_id", "feed_name", "feed_url", "site_url", "category", "unread_count", "created_at", "updated_at"},
+	Row: func(r followRecord) []string {
+		return []string{
+			r.ID, r.FeedID, r.FeedName, r.FeedURL, csvString(r.SiteURL), csvString(r.Category),
+			strconv.FormatInt(r.UnreadCount, 10), csvTime(r.CreatedAt), csvTime(r.UpdatedAt),
+		}
+	},
+}
+
+// postRecord is a browsed post. fetched_at is when the post was first
+// stored, which is what --sort fetched orders by.
+type postRecord struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description *string    `json:"description"`
+	PublishedAt *time.Time `json:"published_at"`
+	FetchedAt   time.Time  `json:"fetched_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	FeedID      string     `json:"feed_id"`
+	FeedName    string     `json:"feed_name"`
+	Category    *string    `json:"category"`
+	ReadAt      *time.Time `json:"read_at"`
+}
+
+func newPostRecord(post browsePost) postRecord {
+	return postRecord{
+		ID:          post.ID.String(),
+		Title:       post.Title,
+		URL:         post.Url,
+		Description: nullString(post.Description),
+		PublishedAt: nullTime(post.PublishedAt),
+		FetchedAt:   post.CreatedAt.UTC(),
+		UpdatedAt:   post.UpdatedAt.UTC(),
+		FeedID:      post.FeedID.String(),
+		FeedName:    post.FeedName,
+		Category:    nullString(post.Category),
+		ReadAt:      nullTime(post.ReadAt),
+	}
+}
+
+var postListing = listing[postRecord]{
+	Columns: []string{"id", "title", "url", "description", "published_at", "fetched_at", "updated_at", "feed_id", "feed_name", "category", "read_at"},
+	Row: func(r postRecord) []string {
+		return []string{
+			r.ID, r.Title, r.URL, csvString(r.Description), csvNullTime(r.PublishedAt),
+			csvTime(r.FetchedAt), csvTime(r.UpdatedAt), r.FeedID, r.FeedName, csvString(r.Category), csvNullTime(r.ReadAt),
+		}
+	},
+}
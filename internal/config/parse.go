@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatRDF  = "rdf"
+	FormatJSON = "json"
+)
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// parseFeed detects the format of a fetched document and dispatches to the
+// matching parser. The Content-Type header is only a hint, servers regularly
+// send feeds as text/html or application/octet-stream, so the body decides.
+func parseFeed(contentType string, body []byte) (*ParsedFeed, error) {
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	format, err := detectFormat(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	var result *ParsedFeed
+	switch format {
+	case FormatRSS:
+		var feed RSSFeed
+		err = xml.Unmarshal(body, &feed)
+		result = feed.normalize()
+	case FormatAtom:
+		var feed AtomFeed
+		err = xml.Unmarshal(body, &feed)
+		result = feed.normalize()
+	case FormatRDF:
+		var feed RDFFeed
+		err = xml.Unmarshal(body, &feed)
+		result = feed.normalize()
+	case FormatJSON:
+		var feed JSONFeed
+		err = json.Unmarshal(body, &feed)
+		result = feed.normalize()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s feed: %w", format, err)
+	}
+	result.Format = format
+	return result, nil
+}
+
+// detectFormat sniffs the document first, servers often label feeds with the
+// wrong type. The content type only decides when the body looks like neither
+// JSON nor XML.
+func detectFormat(contentType string, body []byte) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return "", fmt.Errorf("empty feed document")
+	}
+	isJSON := mediaType == "application/feed+json" || mediaType == "application/json"
+	if trimmed[0] == '{' || (trimmed[0] != '<' && isJSON) {
+		return FormatJSON, nil
+	}
+	root, err := rootElement(trimmed)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case root.Local == "rss":
+		return FormatRSS, nil
+	case root.Local == "feed" && root.Space == atomNamespace:
+		return FormatAtom, nil
+	case root.Local == "RDF" && root.Space == rdfNamespace:
+		return FormatRDF, nil
+	}
+	if strings.Contains(mediaType, "html") {
+		return "", fmt.Errorf("url points to an html page (%s), not a feed", mediaType)
+	}
+	return "", fmt.Errorf("unsupported feed format with root element <%s>", root.Local)
+}
+
+func rootElement(body []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("could not find root element: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
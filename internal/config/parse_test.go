@@ -0,0 +1,244 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>RSS feed</title>
+    <atom:link rel="self" href="https://example.com/rss.xml"/>
+    <link>https://example.com/</link>
+    <description>An RSS 2.0 feed</description>
+    <ttl>60</ttl>
+    <item>
+      <guid>rss-1</guid>
+      <title>First</title>
+      <link>https://example.com/1</link>
+      <description>Summary</description>
+      <content:encoded>&lt;p&gt;Body&lt;/p&gt;</content:encoded>
+      <pubDate>Tue, 10 Jun 2003 04:00:00 GMT</pubDate>
+      <dc:creator>Jane</dc:creator>
+    </item>
+  </channel>
+</rss>`
+
+const testAtom = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Atom feed</title>
+  <subtitle>An Atom feed</subtitle>
+  <link rel="self" href="https://example.com/atom.xml"/>
+  <link href="https://example.com/"/>
+  <author><name>Jane</name></author>
+  <entry>
+    <id>urn:atom-1</id>
+    <title type="html">First &amp;amp; only</title>
+    <link rel="alternate" type="text/html" href="https://example.com/1"/>
+    <summary>Summary</summary>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">Body</div></content>
+    <updated>2024-03-01T09:30:00Z</updated>
+  </entry>
+</feed>`
+
+const testRDF = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+  <channel rdf:about="https://example.com/">
+    <title>RDF feed</title>
+    <link>https://example.com/</link>
+    <description>An RSS 1.0 feed</description>
+    <sy:updatePeriod>daily</sy:updatePeriod>
+  </channel>
+  <item rdf:about="https://example.com/1">
+    <title>First</title>
+    <link>https://example.com/1</link>
+    <description>Summary</description>
+    <dc:date>2024-03-01T09:30:00Z</dc:date>
+    <dc:creator>Jane</dc:creator>
+  </item>
+</rdf:RDF>`
+
+const testJSONFeed = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "JSON feed",
+  "home_page_url": "https://example.com/",
+  "description": "A JSON feed",
+  "authors": [{"name": "Jane"}],
+  "items": [
+    {
+      "id": 1,
+      "external_url": "https://example.com/1",
+      "title": "First",
+      "summary": "Summary",
+      "content_text": "Body",
+      "date_modified": "2024-03-01T09:30:00Z"
+    }
+  ]
+}`
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+		wantErr     bool
+	}{
+		{"rss", "application/rss+xml", testRSS, FormatRSS, false},
+		{"atom", "application/atom+xml", testAtom, FormatAtom, false},
+		{"rdf", "application/rdf+xml", testRDF, FormatRDF, false},
+		{"json feed", "application/feed+json", testJSONFeed, FormatJSON, false},
+		{"rss labelled as html", "text/html; charset=utf-8", testRSS, FormatRSS, false},
+		{"atom labelled as json", "application/json", testAtom, FormatAtom, false},
+		{"json feed labelled as xml", "text/xml", testJSONFeed, FormatJSON, false},
+		{"json without a brace", "application/json", ` []`, FormatJSON, false},
+		{"leading whitespace", "", "\n\n  " + testRSS, FormatRSS, false},
+		{"feed outside the atom namespace", "application/xml", `<feed><title>x</title></feed>`, "", true},
+		{"html page", "text/html", `<!DOCTYPE html><html><body>hi</body></html>`, "", true},
+		{"unknown root", "application/xml", `<opml version="2.0"/>`, "", true},
+		{"empty", "application/rss+xml", "  \n", "", true},
+		{"not xml", "text/plain", "hello", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectFormat(tt.contentType, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("detectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        *ParsedFeed
+	}{
+		{
+			name:        "rss",
+			contentType: "application/rss+xml",
+			body:        testRSS,
+			want: &ParsedFeed{
+				Format:      FormatRSS,
+				Title:       "RSS feed",
+				Link:        "https://example.com/",
+				Description: "An RSS 2.0 feed",
+				UpdateHint:  time.Hour,
+				Items: []FeedItem{{
+					ID:          "rss-1",
+					Title:       "First",
+					Link:        "https://example.com/1",
+					Description: "Summary",
+					Content:     "<p>Body</p>",
+					PubDate:     "Tue, 10 Jun 2003 04:00:00 GMT",
+					Author:      "Jane",
+				}},
+			},
+		},
+		{
+			name:        "atom",
+			contentType: "application/atom+xml",
+			body:        testAtom,
+			want: &ParsedFeed{
+				Format:      FormatAtom,
+				Title:       "Atom feed",
+				Link:        "https://example.com/",
+				Description: "An Atom feed",
+				Items: []FeedItem{{
+					ID:          "urn:atom-1",
+					Title:       "First &amp; only",
+					Link:        "https://example.com/1",
+					Description: "Summary",
+					Content:     `<div xmlns="http://www.w3.org/1999/xhtml">Body</div>`,
+					PubDate:     "2024-03-01T09:30:00Z",
+					Updated:     "2024-03-01T09:30:00Z",
+					Author:      "Jane",
+				}},
+			},
+		},
+		{
+			name:        "rdf",
+			contentType: "application/rdf+xml",
+			body:        testRDF,
+			want: &ParsedFeed{
+				Format:      FormatRDF,
+				Title:       "RDF feed",
+				Link:        "https://example.com/",
+				Description: "An RSS 1.0 feed",
+				UpdateHint:  24 * time.Hour,
+				Items: []FeedItem{{
+					ID:          "https://example.com/1",
+					Title:       "First",
+					Link:        "https://example.com/1",
+					Description: "Summary",
+					PubDate:     "2024-03-01T09:30:00Z",
+					Author:      "Jane",
+				}},
+			},
+		},
+		{
+			name:        "json feed",
+			contentType: "application/feed+json",
+			body:        testJSONFeed,
+			want: &ParsedFeed{
+				Format:      FormatJSON,
+				Title:       "JSON feed",
+				Link:        "https://example.com/",
+				Description: "A JSON feed",
+				Items: []FeedItem{{
+					ID:          "1",
+					Title:       "First",
+					Link:        "https://example.com/1",
+					Description: "Summary",
+					Content:     "Body",
+					PubDate:     "2024-03-01T09:30:00Z",
+					Updated:     "2024-03-01T09:30:00Z",
+					Author:      "Jane",
+				}},
+			},
+		},
+		{
+			name:        "byte order mark",
+			contentType: "application/rss+xml",
+			body:        "\xef\xbb\xbf<rss><channel><title>BOM</title></channel></rss>",
+			want:        &ParsedFeed{Format: FormatRSS, Title: "BOM", Items: []FeedItem{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFeed(tt.contentType, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("parseFeed() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFeed() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"truncated rss", "application/rss+xml", `<rss><channel><title>x</title>`},
+		{"broken json", "application/feed+json", `{"title": `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFeed(tt.contentType, []byte(tt.body))
+			if err == nil {
+				t.Errorf("parseFeed() succeeded, want an error")
+			}
+		})
+	}
+}
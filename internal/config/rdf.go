@@ -0,0 +1,48 @@
+package config
+
+// RDFFeed is an RSS 1.0 document. Unlike RSS 2.0 the items are siblings of
+// the channel element instead of being nested in it.
+type RDFFeed struct {
+	Channel RDFChannel `xml:"http://purl.org/rss/1.0/ channel"`
+	Items   []RDFItem  `xml:"http://purl.org/rss/1.0/ item"`
+}
+
+type RDFChannel struct {
+	Title           string `xml:"title"`
+	Link            string `xml:"link"`
+	Description     string `xml:"description"`
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+type RDFItem struct {
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+func (feed *RDFFeed) normalize() *ParsedFeed {
+	items := []FeedItem{}
+	for _, item := range feed.Items {
+		items = append(items, FeedItem{
+			ID:          item.About,
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			Content:     item.Content,
+			PubDate:     item.Date,
+			Author:      item.Creator,
+		})
+	}
+	return &ParsedFeed{
+		Title:       feed.Channel.Title,
+		Link:        feed.Channel.Link,
+		Description: feed.Channel.Description,
+		Items:       items,
+		UpdateHint:  updateHint("", feed.Channel.UpdatePeriod, feed.Channel.UpdateFrequency),
+	}
+}
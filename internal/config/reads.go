@@ -0,0 +1,100 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"rss-aggregator/internal/database"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// postArg looks up the post whose id is the first argument of the command.
+func postArg(s *State, cmd CommandInput) database.Post {
+	if len(cmd.Args) == 1 {
+		fmt.Println("Post id is required")
+		os.Exit(1)
+	}
+	id, err := uuid.Parse(cmd.Args[1])
+	if err != nil {
+		fmt.Printf("Invalid post id %q\n", cmd.Args[1])
+		os.Exit(1)
+	}
+	post, err := s.Db.GetPost(context.Background(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Printf("Post %s does not exist\n", id)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	return post
+}
+
+func HandlerRead(s *State, cmd CommandInput, user database.User) error {
+	post := postArg(s, cmd)
+	params := database.MarkPostReadParams{UserID: user.ID, PostID: post.ID, ReadAt: time.Now()}
+	err := s.Db.MarkPostRead(context.Background(), params)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Marked %q as read\n", post.Title)
+	return nil
+}
+
+func HandlerUnread(s *State, cmd CommandInput, user database.User) error {
+	post := postArg(s, cmd)
+	params := database.MarkPostUnreadParams{UserID: user.ID, PostID: post.ID}
+	removed, err := s.Db.MarkPostUnread(context.Background(), params)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	if removed == 0 {
+		fmt.Printf("%q was not read yet\n", post.Title)
+		return nil
+	}
+	fmt.Printf("Marked %q as unread\n", post.Title)
+	return nil
+}
+
+// HandlerMarkRead marks posts of the followed feeds as read in bulk. --feed
+// and --before can be combined, --all has to be given explicitly when
+// neither is.
+func HandlerMarkRead(s *State, cmd CommandInput, user database.User) error {
+	fs := flag.NewFlagSet("markread", flag.ContinueOnError)
+	feedURL := fs.String("feed", "", "only mark posts of the feed with this url")
+	all := fs.Bool("all", false, "mark all posts of all followed feeds")
+	before := fs.String("before", "", "only mark posts published before this date")
+	_, err := parseFlags(fs, cmd.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	if *feedURL == "" && *before == "" && !*all {
+		fmt.Println("One of --feed <url>, --before <date> or --all is required")
+		os.Exit(1)
+	}
+	params := database.MarkPostsReadParams{ReadAt: time.Now(), UserID: user.ID}
+	if *feedURL != "" {
+		feed, err := s.Db.GetFeed(context.Background(), *feedURL)
+		if err != nil {
+			fmt.Printf("Error. Feed may not exist. %s\n", err)
+			os.Exit(1)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	params.Before = dateFlag(*before)
+	marked, err := s.Db.MarkPostsRead(context.Background(), params)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Marked %d posts as read\n", marked)
+	return nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"rss-aggregator/internal/database"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	feedEventMoved  = "moved"
+	feedEventMerged = "merged"
+	feedEventGone   = "gone"
+)
+
+// relocateFeed follows a permanent redirect. The stored url is rewritten,
+// or when another feed already uses the new url, follows and posts are
+// merged into that feed and the old row is deleted. The returned feed is the
+// one that now represents the subscription.
+//
+// The steps are not wrapped in a transaction but each one is idempotent, so
+// an interrupted merge is completed by the next fetch.
+func relocateFeed(ctx context.Context, s *State, feed database.Feed, newURL string) (database.Feed, error) {
+	target, err := s.Db.GetFeed(ctx, newURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		params := database.UpdateFeedUrlParams{ID: feed.ID, Url: newURL, UpdatedAt: time.Now()}
+		err = s.Db.UpdateFeedUrl(ctx, params)
+		if err != nil {
+			return feed, err
+		}
+		logFeedEvent(ctx, s, feed, feedEventMoved, fmt.Sprintf("%s -> %s", feed.Url, newURL))
+		fmt.Printf("Feed %s moved permanently to %s\n", feed.Url, newURL)
+		feed.Url = newURL
+		return feed, nil
+	}
+	if err != nil {
+		return feed, err
+	}
+	if target.ID == feed.ID {
+		return feed, nil
+	}
+	followParams := database.MoveFeedFollowsParams{ToFeedID: target.ID, FromFeedID: feed.ID}
+	err = s.Db.MoveFeedFollows(ctx, followParams)
+	if err != nil {
+		return feed, err
+	}
+	postsParams := database.MovePostsParams{ToFeedID: target.ID, FromFeedID: feed.ID}
+	err = s.Db.MovePosts(ctx, postsParams)
+	if err != nil {
+		return feed, err
+	}
+	// Posts the target already has are deleted with the old feed, their
+	// stars are moved over to the target's copy first. Where a user starred
+	// both copies, the notes are merged into the target's star.
+	notesParams := database.MergeSavedPostNotesParams{FromFeedID: feed.ID, ToFeedID: target.ID}
+	err = s.Db.MergeSavedPostNotes(ctx, notesParams)
+	if err != nil {
+		return feed, err
+	}
+	savedParams := database.MoveSavedPostsParams{FromFeedID: feed.ID, ToFeedID: target.ID}
+	err = s.Db.MoveSavedPosts(ctx, savedParams)
+	if err != nil {
+		return feed, err
+	}
+	logFeedEvent(ctx, s, target, feedEventMerged, fmt.Sprintf("%s (%s) merged into %s", feed.Url, feed.ID, target.Url))
+	err = s.Db.DeleteFeed(ctx, feed.ID)
+	if err != nil {
+		return feed, err
+	}
+	fmt.Printf("Feed %s moved permanently to %s and was merged into %s\n", feed.Url, newURL, target.Name)
+	return target, nil
+}
+
+// retireFeed disables a feed whose server answered 410 Gone.
+func retireFeed(ctx context.Context, s *State, feed database.Feed) {
+	params := database.DisableFeedParams{
+		ID:         feed.ID,
+		DisabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	err := s.Db.DisableFeed(ctx, params)
+	if err != nil {
+		fmt.Printf("Error disabling feed %s: %v\n", feed.Url, err)
+		return
+	}
+	logFeedEvent(ctx, s, feed, feedEventGone, "server answered 410 Gone")
+	fmt.Printf("Feed %s is gone and has been disabled\n", feed.Url)
+}
+
+func logFeedEvent(ctx context.Context, s *State, feed database.Feed, kind string, detail string) {
+	params := database.CreateFeedEventParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		FeedID:    uuid.NullUUID{UUID: feed.ID, Valid: true},
+		FeedUrl:   feed.Url,
+		Kind:      kind,
+		Detail:    sql.NullString{String: detail, Valid: detail != ""},
+	}
+	err := s.Db.CreateFeedEvent(ctx, params)
+	if err != nil {
+		fmt.Printf("Error logging %s event for %s: %v\n", kind, feed.Url, err)
+	}
+}
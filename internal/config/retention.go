@@ -0,0 +1,259 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"rss-aggregator/internal/database"
+	"time"
+)
+
+// defaultKeepUnreadDays protects unread posts unless the config sets
+// keep_unread_days.
+const defaultKeepUnreadDays = 30
+
+// retentionPolicy is what is kept of one feed, zero means no limit.
+type retentionPolicy struct {
+	KeepPosts int
+	KeepDays  int
+}
+
+func (p retentionPolicy) String() string {
+	switch {
+	case p.KeepPosts > 0 && p.KeepDays > 0:
+		return fmt.Sprintf("newest %d posts, at most %d days old", p.KeepPosts, p.KeepDays)
+	case p.KeepPosts > 0:
+		return fmt.Sprintf("newest %d posts", p.KeepPosts)
+	case p.KeepDays > 0:
+		return fmt.Sprintf("posts of the last %d days", p.KeepDays)
+	}
+	return "all posts"
+}
+
+func (c *Config) globalRetention() Retention {
+	if c.Retention == nil {
+		return Retention{}
+	}
+	return *c.Retention
+}
+
+func (r Retention) unreadDays() int {
+	if r.KeepUnreadDays > 0 {
+		return r.KeepUnreadDays
+	}
+	return defaultKeepUnreadDays
+}
+
+// feedRetention applies the settings of a feed on top of the global ones.
+// A feed setting of 0 lifts the global limit for that feed.
+func feedRetention(feed database.Feed, global Retention) retentionPolicy {
+	policy := retentionPolicy{KeepPosts: global.KeepPosts, KeepDays: global.KeepDays}
+	if feed.RetentionKeepPosts.Valid {
+		policy.KeepPosts = int(feed.RetentionKeepPosts.Int32)
+	}
+	if feed.RetentionKeepDays.Valid {
+		policy.KeepDays = int(feed.RetentionKeepDays.Int32)
+	}
+	return policy
+}
+
+// retentionCutoff is the publish date before which posts of the feed are
+// not kept, zero when they are kept forever.
+func retentionCutoff(policy retentionPolicy, now time.Time) time.Time {
+	if policy.KeepDays <= 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, -policy.KeepDays)
+}
+
+// scrapeCutoff is the publish date before which items of the feed are not
+// stored, so that pruned posts still listed in the feed document do not come
+// back. With keep-posts that is the date of the oldest post that is kept.
+func scrapeCutoff(ctx context.Context, s *State, feed database.Feed, now time.Time) time.Time {
+	policy := feedRetention(feed, s.Config.globalRetention())
+	cutoff := retentionCutoff(policy, now)
+	if policy.KeepPosts <= 0 {
+		return cutoff
+	}
+	params := database.GetKeepPostsCutoffParams{FeedID: feed.ID, Offset: int32(policy.KeepPosts - 1)}
+	oldest, err := s.Db.GetKeepPostsCutoff(ctx, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cutoff // fewer posts than are kept
+	}
+	if err != nil {
+		fmt.Printf("Error reading retention of %s: %v\n", feed.Url, err)
+		return cutoff
+	}
+	oldestAt := oldest.CreatedAt
+	if oldest.PublishedAt.Valid {
+		oldestAt = oldest.PublishedAt.Time
+	}
+	if oldestAt.After(cutoff) {
+		return oldestAt
+	}
+	return cutoff
+}
+
+// pruneFeeds deletes the posts every feed's policy no longer keeps, or only
+// lists them when dryRun is set. Starred posts and posts a follower has not
+// read yet within the keep_unread_days window are never deleted.
+func pruneFeeds(ctx context.Context, s *State, dryRun bool) (int64, error) {
+	feeds, err := s.Db.GetAllFeeds(ctx)
+	if err != nil {
+		return 0, err
+	}
+	global := s.Config.globalRetention()
+	now := time.Now()
+	total := int64(0)
+	for _, feed := range feeds {
+		policy := feedRetention(feed, global)
+		if policy.KeepPosts == 0 && policy.KeepDays == 0 {
+			continue
+		}
+		params := database.PrunePostsParams{
+			FeedID:          feed.ID,
+			KeepPosts:       int32(policy.KeepPosts),
+			PublishedBefore: sql.NullTime{Time: retentionCutoff(policy, now), Valid: true},
+			UnreadSince:     sql.NullTime{Time: now.AddDate(0, 0, -global.unreadDays()), Valid: true},
+		}
+		if dryRun {
+			posts, err := s.Db.GetPrunablePosts(ctx, database.GetPrunablePostsParams(params))
+			if err != nil {
+				return total, err
+			}
+			if len(posts) == 0 {
+				continue
+			}
+			fmt.Printf("%s (%s): %d posts\n", feed.Name, policy, len(posts))
+			for _, post := range posts {
+				published := "-"
+				if post.PublishedAt.Valid {
+					published = post.PublishedAt.Time.Local().Format(time.DateOnly)
+				}
+				fmt.Printf("  - %s %s\n", published, post.Title)
+			}
+			total += int64(len(posts))
+			continue
+		}
+		pruned, err := s.Db.PrunePosts(ctx, params)
+		if err != nil {
+			return total, err
+		}
+		if pruned > 0 {
+			fmt.Printf("Pruned %d posts of %s (%s)\n", pruned, feed.Name, policy)
+		}
+		total += pruned
+	}
+	return total, nil
+}
+
+func HandlerPrune(s *State, cmd CommandInput) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "list the posts that would be deleted without deleting them")
+	_, err := parseFlags(fs, cmd.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	total, err := pruneFeeds(context.Background(), s, *dryRun)
+	if err != nil {
+		fmt.Printf("Error pruning posts. %s\n", err)
+		os.Exit(1)
+	}
+	if *dryRun {
+		fmt.Printf("%d posts would be pruned\n", total)
+		return nil
+	}
+	fmt.Printf("%d posts pruned\n", total)
+	return nil
+}
+
+// HandlerRetention shows or changes the retention settings. Without a feed
+// url the global settings in the config file are changed.
+func HandlerRetention(s *State, cmd CommandInput) error {
+	fs := flag.NewFlagSet("retention", flag.ContinueOnError)
+	keepPosts := fs.Int("keep-posts", 0, "keep the newest N posts per feed (0 keeps all)")
+	keepDays := fs.Int("keep-days", 0, "keep posts for D days (0 keeps them forever)")
+	keepUnreadDays := fs.Int("keep-unread-days", 0, fmt.Sprintf("never prune unread posts younger than D days, global only (default %d)", defaultKeepUnreadDays))
+	clear := fs.Bool("clear", false, "drop the settings of the feed so the global ones apply")
+	args, err := parseFlags(fs, cmd.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if *keepPosts < 0 || *keepDays < 0 || *keepUnreadDays < 0 {
+		fmt.Println("Retention settings must not be negative")
+		os.Exit(1)
+	}
+	if set["keep-unread-days"] && *keepUnreadDays == 0 {
+		fmt.Println("--keep-unread-days must be at least 1")
+		os.Exit(1)
+	}
+	global := s.Config.globalRetention()
+
+	if len(args) == 0 {
+		if set["clear"] {
+			fmt.Println("--clear needs a feed url")
+			os.Exit(1)
+		}
+		if set["keep-posts"] {
+			global.KeepPosts = *keepPosts
+		}
+		if set["keep-days"] {
+			global.KeepDays = *keepDays
+		}
+		if set["keep-unread-days"] {
+			global.KeepUnreadDays = *keepUnreadDays
+		}
+		if len(set) > 0 {
+			s.Config.SetRetention(global)
+		}
+		fmt.Printf("Global retention: %s, unread posts of the last %d days are kept\n",
+			retentionPolicy{KeepPosts: global.KeepPosts, KeepDays: global.KeepDays}, global.unreadDays())
+		return nil
+	}
+
+	feed, err := s.Db.GetFeed(context.Background(), args[0])
+	if err != nil {
+		fmt.Printf("Error. Feed may not exist. %s\n", err)
+		os.Exit(1)
+	}
+	if set["keep-unread-days"] {
+		fmt.Println("--keep-unread-days can only be set globally")
+		os.Exit(1)
+	}
+	if len(set) > 0 {
+		params := database.SetFeedRetentionParams{
+			ID:                 feed.ID,
+			RetentionKeepPosts: feed.RetentionKeepPosts,
+			RetentionKeepDays:  feed.RetentionKeepDays,
+			UpdatedAt:          time.Now(),
+		}
+		if *clear {
+			params.RetentionKeepPosts = sql.NullInt32{}
+			params.RetentionKeepDays = sql.NullInt32{}
+		}
+		if set["keep-posts"] {
+			params.RetentionKeepPosts = sql.NullInt32{Int32: int32(*keepPosts), Valid: true}
+		}
+		if set["keep-days"] {
+			params.RetentionKeepDays = sql.NullInt32{Int32: int32(*keepDays), Valid: true}
+		}
+		err = s.Db.SetFeedRetention(context.Background(), params)
+		if err != nil {
+			fmt.Printf("Error %s\n", err)
+			os.Exit(1)
+		}
+		feed.RetentionKeepPosts = params.RetentionKeepPosts
+		feed.RetentionKeepDays = params.RetentionKeepDays
+	}
+	source := "global setting"
+	if feed.RetentionKeepPosts.Valid || feed.RetentionKeepDays.Valid {
+		source = "feed setting"
+	}
+	fmt.Printf("Retention of %s: %s (%s)\n", feed.Name, feedRetention(feed, global), source)
+	return nil
+}
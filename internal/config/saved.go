@@ -0,0 +1,139 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"rss-aggregator/internal/database"
+	"strings"
+	"time"
+)
+
+// HandlerStar saves a post for the user. Everything after the post id is
+// the note, starring a post again replaces the note only when one is given.
+// Flags go before the post id so a note can contain anything.
+func HandlerStar(s *State, cmd CommandInput, user database.User) error {
+	fs := flag.NewFlagSet("star", flag.ContinueOnError)
+	clearNote := fs.Bool("clear-note", false, "remove the note of the post")
+	err := fs.Parse(cmd.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	args := fs.Args()
+	post := postArg(s, CommandInput{Name: cmd.Name, Args: append([]string{cmd.Args[0]}, args...)})
+	note := strings.TrimSpace(strings.Join(args[1:], " "))
+	if *clearNote && note != "" {
+		fmt.Println("--clear-note does not take a note")
+		os.Exit(1)
+	}
+	params := database.SavePostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		CreatedAt: time.Now(),
+		Note:      sql.NullString{String: note, Valid: note != ""},
+		ClearNote: *clearNote,
+	}
+	err = s.Db.SavePost(context.Background(), params)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Starred %q\n", post.Title)
+	return nil
+}
+
+func HandlerUnstar(s *State, cmd CommandInput, user database.User) error {
+	post := postArg(s, cmd)
+	params := database.UnsavePostParams{UserID: user.ID, PostID: post.ID}
+	removed, err := s.Db.UnsavePost(context.Background(), params)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	if removed == 0 {
+		fmt.Printf("%q was not starred\n", post.Title)
+		return nil
+	}
+	fmt.Printf("Unstarred %q\n", post.Title)
+	return nil
+}
+
+func HandlerStarred(s *State, cmd CommandInput, user database.User) error {
+	saved, err := s.Db.GetSavedPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	if len(saved) == 0 {
+		fmt.Printf("%s has no starred posts\n", user.Name)
+		return nil
+	}
+	fmt.Printf("Starred posts of %s:\n", user.Name)
+	for _, post := range saved {
+		fmt.Printf("* %s (%s)\n  %s\n  ID: %s, starred %s\n", post.Title, post.FeedName, post.Url, post.ID, post.SavedAt.Format(time.DateTime))
+		if post.Note.Valid {
+			fmt.Printf("  Note: %s\n", post.Note.String)
+		}
+	}
+	return nil
+}
+
+// starredPost is the exported form of a starred post.
+type starredPost struct {
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Feed        string     `json:"feed"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	StarredAt   time.Time  `json:"starred_at"`
+	Note        string     `json:"note,omitempty"`
+}
+
+// exportStarred writes the starred posts of the user with their notes as
+// JSON to the file given as first argument, or to stdout.
+func exportStarred(s *State, args []string, user database.User) error {
+	saved, err := s.Db.GetSavedPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	posts := []starredPost{}
+	for _, post := range saved {
+		exported := starredPost{
+			Title:     post.Title,
+			URL:       post.Url,
+			Feed:      post.FeedName,
+			StarredAt: post.SavedAt,
+			Note:      post.Note.String,
+		}
+		if post.PublishedAt.Valid {
+			exported.PublishedAt = &post.PublishedAt.Time
+		}
+		posts = append(posts, exported)
+	}
+	if len(args) == 0 {
+		return writeJSON(os.Stdout, posts)
+	}
+	file, err := os.Create(args[0])
+	if err != nil {
+		fmt.Printf("Error creating %s. %s\n", args[0], err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	err = writeJSON(file, posts)
+	if err != nil {
+		fmt.Printf("Error writing %s. %s\n", args[0], err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d starred posts to %s\n", len(posts), args[0])
+	return nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// claimLease keeps a claimed feed away from other agg processes until the
+// fetch finishes and the real next fetch time is written.
+const claimLease = 15 * time.Minute
+
+// recentPostsForSchedule is how many publish times the posting frequency
+// estimate looks at.
+const recentPostsForSchedule = 20
+
+type scheduleBounds struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// nextFetchInterval combines what the publisher tells us with how often
+// the feed actually posts. Publisher hints (<ttl>, <sy:updatePeriod>,
+// Cache-Control, Expires) are treated as "do not come back sooner", the
+// observed frequency is polled at twice the posting rate.
+func nextFetchInterval(hints []time.Duration, publishTimes []time.Time, bounds scheduleBounds) time.Duration {
+	interval := bounds.Min
+	if gap := medianGap(publishTimes); gap > 0 {
+		interval = gap / 2
+	}
+	for _, hint := range hints {
+		interval = max(interval, hint)
+	}
+	return min(max(interval, bounds.Min), bounds.Max)
+}
+
+func medianGap(publishTimes []time.Time) time.Duration {
+	if len(publishTimes) < 2 {
+		return 0
+	}
+	sorted := slices.Clone(publishTimes)
+	slices.SortFunc(sorted, func(a, b time.Time) int { return a.Compare(b) })
+	gaps := []time.Duration{}
+	for i := 1; i < len(sorted); i++ {
+		if gap := sorted[i].Sub(sorted[i-1]); gap > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) == 0 {
+		return 0
+	}
+	slices.Sort(gaps)
+	return gaps[len(gaps)/2]
+}
+
+// updateHint reads <ttl> (minutes) and the syndication module's
+// updatePeriod/updateFrequency pair, e.g. hourly and 2 means every 30m.
+func updateHint(ttl, period, frequency string) time.Duration {
+	hint := time.Duration(0)
+	if minutes, err := strconv.Atoi(strings.TrimSpace(ttl)); err == nil && minutes > 0 {
+		hint = time.Duration(minutes) * time.Minute
+	}
+	periods := map[string]time.Duration{
+		"hourly":  time.Hour,
+		"daily":   24 * time.Hour,
+		"weekly":  7 * 24 * time.Hour,
+		"monthly": 30 * 24 * time.Hour,
+		"yearly":  365 * 24 * time.Hour,
+	}
+	length, ok := periods[strings.ToLower(strings.TrimSpace(period))]
+	if !ok {
+		return hint
+	}
+	times, err := strconv.Atoi(strings.TrimSpace(frequency))
+	if err != nil || times < 1 {
+		times = 1
+	}
+	return max(hint, length/time.Duration(times))
+}
+
+// cacheLifetime reads how long the response may be cached, preferring
+// Cache-Control max-age over Expires like an HTTP cache would.
+func cacheLifetime(header http.Header, now time.Time) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(directive), "=")
+		if !found || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		return 0
+	}
+	expires, err := http.ParseTime(header.Get("Expires"))
+	if err != nil {
+		return 0
+	}
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		date = now
+	}
+	return max(expires.Sub(date), 0)
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"rss-aggregator/internal/database"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// searchQuery translates what the user typed into a to_tsquery expression.
+// Words are and-ed, a trailing * makes a word a prefix and double quotes
+// group words into a phrase: `"rust async" tok*` becomes
+// "(rust <-> async) & tok:*". Everything but letters and digits separates
+// words, so the result is always a valid expression.
+func searchQuery(input string) (string, error) {
+	terms := []string{}
+	for i, part := range strings.Split(input, `"`) {
+		phrase := i%2 == 1 // inside quotes
+		if phrase {
+			if term := searchTerm(part); term != "" {
+				terms = append(terms, term)
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			if term := searchTerm(field); term != "" {
+				terms = append(terms, term)
+			}
+		}
+	}
+	if len(terms) == 0 {
+		return "", fmt.Errorf("search query %q contains no words", input)
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+// searchTerm turns a word or phrase into a tsquery operand. Punctuation
+// inside a word, as in "node.js", makes it a phrase of its parts.
+func searchTerm(text string) string {
+	prefix := strings.HasSuffix(strings.TrimSpace(text), "*")
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	if prefix {
+		words[len(words)-1] += ":*"
+	}
+	if len(words) == 1 {
+		return words[0]
+	}
+	return "(" + strings.Join(words, " <-> ") + ")"
+}
+
+var (
+	snippetTag        = regexp.MustCompile(`<[^>]*>`)
+	snippetPartialTag = regexp.MustCompile(`^[^<]*?>|<[^>]*$`)
+)
+
+// cleanSnippet removes markup the snippet was cut out of, including tags
+// cut in half at either end.
+func cleanSnippet(snippet string) string {
+	snippet = snippetTag.ReplaceAllString(snippet, " ")
+	snippet = snippetPartialTag.ReplaceAllString(snippet, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(snippet)), " ")
+}
+
+func HandlerSearch(s *State, cmd CommandInput, user database.User) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	all := fs.Bool("all", false, "search the posts of all feeds, not only followed ones")
+	feedURL := fs.String("feed", "", "only search the feed with this url")
+	since := fs.String("since", "", "only posts published on or after this date")
+	until := fs.String("until", "", "only posts published before this date")
+	limit := fs.Int("limit", 10, "maximum number of results")
+	args, err := parseFlags(fs, cmd.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	if len(args) == 0 {
+		fmt.Println("Search query is required")
+		os.Exit(1)
+	}
+	if *limit < 1 {
+		fmt.Println("Limit must be at least 1")
+		os.Exit(1)
+	}
+	query, err := searchQuery(strings.Join(args, " "))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	params := database.SearchPostsParams{
+		Query:      query,
+		AllFeeds:   *all,
+		UserID:     user.ID,
+		MaxResults: int32(*limit),
+	}
+	if *feedURL != "" {
+		feed, err := s.Db.GetFeed(context.Background(), *feedURL)
+		if err != nil {
+			fmt.Printf("Error. Feed may not exist. %s\n", err)
+			os.Exit(1)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	params.Since = dateFlag(*since)
+	params.Until = dateFlag(*until)
+	results, err := s.Db.SearchPosts(context.Background(), params)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	if len(results) == 0 {
+		fmt.Println("No posts found")
+		return nil
+	}
+	for _, post := range results {
+		published := "-"
+		if post.PublishedAt.Valid {
+			published = post.PublishedAt.Time.Local().Format(time.DateOnly)
+		}
+		fmt.Printf("* %s (%s, %s)\n", post.Title, post.FeedName, published)
+		if snippet := cleanSnippet(post.Snippet); snippet != "" {
+			fmt.Printf("  %s\n", snippet)
+		}
+		fmt.Printf("  %s\n  ID: %s\n", post.Url, post.ID)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	shutdownTimeout = 10 * time.Second
+	maxBodyBytes    = 1 << 20
+)
+
+// HandlerServe serves the JSON API until SIGINT or SIGTERM, then waits for
+// running requests to finish.
+func HandlerServe(s *State, cmd CommandInput) error {
+	if len(cmd.Args) == 1 {
+		fmt.Println("Address is required (e.g. localhost:8080)")
+		os.Exit(1)
+	}
+	server := &http.Server{
+		Addr:              cmd.Args[1],
+		Handler:           logRequests(newAPI(s).routes()),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	fmt.Printf("Serving the API on %s\n", server.Addr)
+
+	select {
+	case err := <-serveErr:
+		fmt.Printf("Error serving the API. %s\n", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		fmt.Printf("Error stopping the server. %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Server stopped")
+	return nil
+}
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		log.Printf("%s %s %d %s", r.Method, redactedURI(r), recorder.status, time.Since(start).Round(time.Microsecond))
+	})
+}
+
+// queryCredentials are the query parameters clients that cannot set headers
+// send their token in.
+var queryCredentials = []string{"token", "api_key"}
+
+// redactedURI is the request uri with the credentials some clients send in
+// the query hidden, so they do not end up in logs.
+func redactedURI(r *http.Request) string {
+	query := r.URL.Query()
+	redacted := false
+	for _, name := range queryCredentials {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return r.URL.RequestURI()
+	}
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// headerRecorder keeps the status and headers of a response and drops the
+// body.
+type headerRecorder struct {
+	header http.Header
+	status int
+}
+
+func (r *headerRecorder) Header() http.Header         { return r.header }
+func (r *headerRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (r *headerRecorder) WriteHeader(status int)      { r.status = status }
+
+// jsonErrors answers requests that match no route with a JSON error instead
+// of the plain text of http.ServeMux, keeping its 404 or 405 status and the
+// Allow header.
+func jsonErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		recorder := &headerRecorder{header: http.Header{}, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		switch recorder.status {
+		case http.StatusNotFound:
+			writeError(w, http.StatusNotFound, "%s not found", r.URL.Path)
+		case http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", recorder.header.Get("Allow"))
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed for %s", r.Method, r.URL.Path)
+		default:
+			handler.ServeHTTP(w, r)
+		}
+	})
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func writeResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Printf("Error writing response. %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeResponse(w, status, apiError{Error: fmt.Sprintf(format, args...)})
+}
+
+// writeInternalError logs the cause and hides it from the client.
+func writeInternalError(w http.ResponseWriter, err error) {
+	log.Printf("Error handling request. %s", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+// decodeBody reads the json request body into v. On failure the error
+// response is written and false returned.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body is larger than %d bytes", maxBodyBytes)
+		return false
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body. %s", err)
+		return false
+	}
+	return true
+}
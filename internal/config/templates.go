@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const defaultBrowseTemplate = "full"
+
+// builtinTemplates are the browse layouts that need no configuration. A
+// template of the same name in the config file takes precedence.
+var builtinTemplates = map[string]string{
+	"compact": `{{if .Read}} {{else}}*{{end}} {{date "2006-01-02" .PublishedAt}}  {{.Title}} ({{.FeedName}}) {{.URL}}
+`,
+	"full": `#########
+{{.Title}}
+#########
+
+{{or .Description "Empty"}}
+
+Continue: {{.URL}}
+ID: {{.ID}}
+
+`,
+	"markdown": `## [{{.Title}}]({{.URL}})
+
+*{{.FeedName}}{{with .PublishedAt}}, {{date "2006-01-02 15:04" .}}{{end}}{{if .Read}}, read{{end}}*
+
+{{with text .Description}}{{truncate 280 .}}
+
+{{end}}`,
+}
+
+// postView is what browse templates are rendered with, once per post.
+// Missing dates are nil.
+type postView struct {
+	ID          string
+	Title       string
+	URL         string
+	Description string
+	FeedName    string
+	Category    string
+	PublishedAt *time.Time
+	FetchedAt   time.Time
+	Read        bool
+	ReadAt      *time.Time
+}
+
+func newPostView(post browsePost) postView {
+	return postView{
+		ID:          post.ID.String(),
+		Title:       post.Title,
+		URL:         post.Url,
+		Description: post.Description.String,
+		FeedName:    post.FeedName,
+		Category:    post.Category.String,
+		PublishedAt: nullTime(post.PublishedAt),
+		FetchedAt:   post.CreatedAt,
+		Read:        post.ReadAt.Valid,
+		ReadAt:      nullTime(post.ReadAt),
+	}
+}
+
+var templateFuncs = template.FuncMap{
+	// date formats a time in local time with a Go layout, "-" when missing.
+	"date": func(layout string, value any) string {
+		var t time.Time
+		switch value := value.(type) {
+		case time.Time:
+			t = value
+		case *time.Time:
+			if value != nil {
+				t = *value
+			}
+		}
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Local().Format(layout)
+	},
+	// text strips the markup of a description.
+	"text": cleanSnippet,
+	// truncate shortens text to at most n characters.
+	"truncate": func(n int, text string) string {
+		runes := []rune(text)
+		if len(runes) <= n {
+			return text
+		}
+		if n < 1 {
+			return ""
+		}
+		return strings.TrimSpace(string(runes[:n-1])) + "…"
+	},
+}
+
+// browseTemplate looks up a template by name, first in the config file and
+// then among the built-in ones. A value containing "{{" is used as the
+// template text itself.
+func browseTemplate(c *Config, name string) (*template.Template, error) {
+	text, ok := c.Templates[name]
+	if !ok {
+		text, ok = builtinTemplates[name]
+	}
+	if !ok && strings.Contains(name, "{{") {
+		text, name, ok = name, "inline", true
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q, use one of %s", name, strings.Join(templateNames(c), ", "))
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q. %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func templateNames(c *Config) []string {
+	names := []string{}
+	for name := range builtinTemplates {
+		names = append(names, name)
+	}
+	for name := range c.Templates {
+		if _, ok := builtinTemplates[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderPosts renders every post with the template. Each post ends with a
+// newline, so templates written as one-liners in the config file do not need
+// their own.
+func renderPosts(w io.Writer, tmpl *template.Template, posts []browsePost) error {
+	for _, post := range posts {
+		var out strings.Builder
+		err := tmpl.Execute(&out, newPostView(post))
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(out.String(), "\n") {
+			out.WriteString("\n")
+		}
+		_, err = io.WriteString(w, out.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printPosts renders the posts to stdout, exiting on template errors.
+func printPosts(tmpl *template.Template, posts []browsePost) {
+	err := renderPosts(os.Stdout, tmpl, posts)
+	if err != nil {
+		fmt.Printf("Error rendering template. %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"rss-aggregator/internal/database"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	timelineRSS          = "rss"
+	timelineAtom         = "atom"
+	defaultTimelineLimit = 50
+	timelineGenerator    = "rss-aggregator"
+)
+
+// timelineOptions select the posts of a timeline document and how it is
+// written. SelfURL is where the document can be fetched from, it is left out
+// when unknown.
+type timelineOptions struct {
+	Format   string
+	Category string
+	Limit    int
+	SelfURL  string
+}
+
+// The output types are separate from RSSFeed and AtomFeed, which are lenient
+// enough to parse what is found in the wild but would not write valid
+// documents.
+
+type rssDocument struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	AtomSpace string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string       `xml:"title"`
+	Link          string       `xml:"link,omitempty"`
+	Description   string       `xml:"description"`
+	SelfLink      *atomLinkOut `xml:"atom:link"`
+	LastBuildDate string       `xml:"lastBuildDate,omitempty"`
+	Generator     string       `xml:"generator"`
+	Items         []rssItemOut `xml:"item"`
+}
+
+type rssItemOut struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	Category    string  `xml:"category,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomDocument struct {
+	XMLName   xml.Name       `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string         `xml:"id"`
+	Title     string         `xml:"title"`
+	Updated   string         `xml:"updated"`
+	Links     []atomLinkOut  `xml:"link"`
+	Author    atomAuthorOut  `xml:"author"`
+	Generator string         `xml:"generator"`
+	Entries   []atomEntryOut `xml:"entry"`
+}
+
+type atomLinkOut struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthorOut struct {
+	Name string `xml:"name"`
+}
+
+type atomEntryOut struct {
+	ID        string           `xml:"id"`
+	Title     string           `xml:"title"`
+	Link      atomLinkOut      `xml:"link"`
+	Updated   string           `xml:"updated"`
+	Published string           `xml:"published,omitempty"`
+	Author    atomAuthorOut    `xml:"author"`
+	Category  *atomCategoryOut `xml:"category"`
+	Summary   *atomSummaryOut  `xml:"summary"`
+}
+
+type atomCategoryOut struct {
+	Term string `xml:"term,attr"`
+}
+
+// atomSummaryOut carries the description as escaped html, the way feeds
+// deliver it.
+type atomSummaryOut struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// timelineTitle names the timeline of a user, or one category of it.
+func timelineTitle(user database.User, category string) string {
+	if category == "" {
+		return fmt.Sprintf("%s's timeline", user.Name)
+	}
+	return fmt.Sprintf("%s's timeline: %s", user.Name, category)
+}
+
+// timelineID stays the same for a user and category wherever the document is
+// served from, so readers do not see a new feed when the address changes.
+func timelineID(user database.User, category string) string {
+	return "urn:uuid:" + uuid.NewSHA1(user.ID, []byte(category)).String()
+}
+
+// postTime is the published date of a post, or the date it was fetched when
+// the feed had none.
+func postTime(post browsePost) time.Time {
+	if post.PublishedAt.Valid {
+		return post.PublishedAt.Time.UTC()
+	}
+	return post.CreatedAt.UTC()
+}
+
+// timelineUpdated is when a post of the timeline was last fetched or changed,
+// or now for an empty timeline. Published dates are not enough, a feed
+// followed later brings in posts published long before.
+func timelineUpdated(posts []browsePost) time.Time {
+	if len(posts) == 0 {
+		return time.Now().UTC()
+	}
+	updated := posts[0].UpdatedAt
+	for _, post := range posts[1:] {
+		if post.UpdatedAt.After(updated) {
+			updated = post.UpdatedAt
+		}
+	}
+	return updated.UTC()
+}
+
+// timelinePosts reads the newest posts of the followed feeds, optionally of
+// one category and its subcategories.
+func timelinePosts(ctx context.Context, s *State, user database.User, category string, limit int) ([]browsePost, error) {
+	params := database.BrowsePostsByPublishedParams{
+		UserID:     user.ID,
+		Category:   sql.NullString{String: category, Valid: category != ""},
+		MaxResults: int32(limit),
+	}
+	return s.Db.BrowsePostsByPublished(ctx, params)
+}
+
+func buildRSS(user database.User, opts timelineOptions, posts []browsePost) *rssDocument {
+	doc := &rssDocument{
+		Version:   "2.0",
+		AtomSpace: atomNamespace,
+		Channel: rssChannel{
+			Title:         timelineTitle(user, opts.Category),
+			Link:          opts.SelfURL,
+			Description:   fmt.Sprintf("Posts of the feeds %s follows", user.Name),
+			LastBuildDate: timelineUpdated(posts).Format(time.RFC1123Z),
+			Generator:     timelineGenerator,
+			Items:         []rssItemOut{},
+		},
+	}
+	if opts.SelfURL != "" {
+		doc.Channel.SelfLink = &atomLinkOut{Href: opts.SelfURL, Rel: "self", Type: "application/rss+xml"}
+	}
+	for _, post := range posts {
+		doc.Channel.Items = append(doc.Channel.Items, rssItemOut{
+			Title:       post.Title,
+			Link:        post.Url,
+			Description: post.Description.String,
+			GUID:        rssGUID{Value: "urn:uuid:" + post.ID.String()},
+			PubDate:     postTime(post).Format(time.RFC1123Z),
+			Category:    post.Category.String,
+		})
+	}
+	return doc
+}
+
+func buildAtom(user database.User, opts timelineOptions, posts []browsePost) *atomDocument {
+	doc := &atomDocument{
+		ID:        timelineID(user, opts.Category),
+		Title:     timelineTitle(user, opts.Category),
+		Updated:   timelineUpdated(posts).Format(time.RFC3339),
+		Author:    atomAuthorOut{Name: user.Name},
+		Generator: timelineGenerator,
+		Entries:   []atomEntryOut{},
+	}
+	if opts.SelfURL != "" {
+		doc.Links = append(doc.Links, atomLinkOut{Href: opts.SelfURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	for _, post := range posts {
+		entry := atomEntryOut{
+			ID:      "urn:uuid:" + post.ID.String(),
+			Title:   post.Title,
+			Link:    atomLinkOut{Href: post.Url, Rel: "alternate"},
+			Updated: postTime(post).Format(time.RFC3339),
+			Author:  atomAuthorOut{Name: post.FeedName},
+		}
+		if post.PublishedAt.Valid {
+			entry.Published = post.PublishedAt.Time.UTC().Format(time.RFC3339)
+		}
+		if post.Category.Valid {
+			entry.Category = &atomCategoryOut{Term: post.Category.String}
+		}
+		if post.Description.String != "" {
+			entry.Summary = &atomSummaryOut{Type: "html", Text: post.Description.String}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return doc
+}
+
+// timelineContentType is the media type a timeline document is served with.
+func timelineContentType(format string) string {
+	if format == timelineAtom {
+		return "application/atom+xml; charset=utf-8"
+	}
+	return "application/rss+xml; charset=utf-8"
+}
+
+// writeTimeline writes the posts as an RSS 2.0 or Atom document. encoding/xml
+// escapes the text and replaces characters XML does not allow.
+func writeTimeline(w io.Writer, user database.User, opts timelineOptions, posts []browsePost) error {
+	var doc any = buildRSS(user, opts, posts)
+	if opts.Format == timelineAtom {
+		doc = buildAtom(user, opts, posts)
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
+
+// exportTimeline writes the newest posts of the user as a feed, to the file
+// given as argument or to stdout.
+func exportTimeline(s *State, args []string, user database.User) error {
+	fs := flag.NewFlagSet("export feed", flag.ContinueOnError)
+	format := fs.String("format", timelineRSS, "document format: rss|atom")
+	category := fs.String("category", "", "only export posts of feeds in this category or its subcategories")
+	limit := fs.Int("limit", defaultTimelineLimit, "number of posts to export")
+	selfURL := fs.String("self-url", "", "url the document will be published at, used for its self link")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		os.Exit(1)
+	}
+	if *format != timelineRSS && *format != timelineAtom {
+		fmt.Printf("Invalid format %q, use rss or atom\n", *format)
+		os.Exit(1)
+	}
+	if *limit < 1 {
+		fmt.Println("Limit must be at least 1")
+		os.Exit(1)
+	}
+	if *selfURL != "" && !validFeedURL(*selfURL) {
+		fmt.Printf("Invalid self url %q\n", *selfURL)
+		os.Exit(1)
+	}
+	opts := timelineOptions{
+		Format:   *format,
+		Category: strings.Trim(*category, categorySeparator),
+		Limit:    *limit,
+		SelfURL:  *selfURL,
+	}
+	posts, err := timelinePosts(context.Background(), s, user, opts.Category, opts.Limit)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	if len(args) == 0 {
+		return writeTimeline(os.Stdout, user, opts, posts)
+	}
+	file, err := os.Create(args[0])
+	if err != nil {
+		fmt.Printf("Error creating %s. %s\n", args[0], err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	err = writeTimeline(file, user, opts, posts)
+	if err != nil {
+		fmt.Printf("Error writing %s. %s\n", args[0], err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d posts to %s\n", len(posts), args[0])
+	return nil
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"rss-aggregator/internal/database"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The prefixes make tokens recognizable, e.g. by secret scanners.
+const (
+	apiTokenPrefix     = "rss_"
+	sessionTokenPrefix = "rss_session_"
+)
+
+// newToken returns a random token and the hash that is stored in its place.
+func newToken(prefix string) (string, string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", "", err
+	}
+	token := prefix + base64.RawURLEncoding.EncodeToString(secret)
+	return token, hashToken(token), nil
+}
+
+// hashToken is a plain sha256, tokens are random enough not to need a slow
+// password hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseExpiry reads a token lifetime such as "720h" or "30d".
+func parseExpiry(value string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid expiry %q, expected e.g. 30d or 12h", value)
+	var expiry time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, invalid
+		}
+		expiry = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, invalid
+		}
+		expiry = d
+	}
+	if expiry <= 0 {
+		return 0, invalid
+	}
+	return expiry, nil
+}
+
+func HandlerToken(s *State, cmd CommandInput, user database.User) error {
+	if len(cmd.Args) == 1 {
+		fmt.Println("Token command is required: create, list or revoke")
+		os.Exit(1)
+	}
+	switch cmd.Args[1] {
+	case "create":
+		return createToken(s, cmd.Args[2:], user)
+	case "list":
+		return listTokens(s, user)
+	case "revoke":
+		return revokeToken(s, cmd.Args[2:], user)
+	}
+	fmt.Printf("Unknown token command %q\n", cmd.Args[1])
+	os.Exit(1)
+	return nil
+}
+
+func createToken(s *State, args []string, user database.User) error {
+	fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+	name := fs.String("name", "api", "label to tell tokens apart")
+	expires := fs.String("expires", "", "lifetime of the token, e.g. 30d or 12h (default: never expires)")
+	_, err := parseFlags(fs, args)
+	if err != nil {
+		os.Exit(1)
+	}
+	now := time.Now()
+	params := database.CreateApiTokenParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Name:      *name,
+		CreatedAt: now,
+	}
+	if *expires != "" {
+		expiry, err := parseExpiry(*expires)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		params.ExpiresAt = sql.NullTime{Time: now.Add(expiry), Valid: true}
+	}
+	token, hash, err := newToken(apiTokenPrefix)
+	if err != nil {
+		fmt.Printf("Error generating token. %s\n", err)
+		os.Exit(1)
+	}
+	params.TokenHash = hash
+	created, err := s.Db.CreateApiToken(context.Background(), params)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created token %q (%s) for %s, %s\n", created.Name, created.ID, user.Name, tokenExpiry(created))
+	fmt.Println(token)
+	fmt.Println("Store it now, it cannot be shown again.")
+	return nil
+}
+
+func tokenExpiry(token database.ApiToken) string {
+	if !token.ExpiresAt.Valid {
+		return "never expires"
+	}
+	if token.ExpiresAt.Time.Before(time.Now()) {
+		return "expired " + token.ExpiresAt.Time.Local().Format(time.DateTime)
+	}
+	return "expires " + token.ExpiresAt.Time.Local().Format(time.DateTime)
+}
+
+func listTokens(s *State, user database.User) error {
+	tokens, err := s.Db.GetApiTokensForUser(context.Background(), user.ID)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	if len(tokens) == 0 {
+		fmt.Printf("%s has no tokens\n", user.Name)
+		return nil
+	}
+	fmt.Printf("Tokens of %s:\n", user.Name)
+	for _, token := range tokens {
+		lastUsed := "never used"
+		if token.LastUsedAt.Valid {
+			lastUsed = "last used " + token.LastUsedAt.Time.Local().Format(time.DateTime)
+		}
+		fmt.Printf("* %s (%s)\n  created %s, %s, %s\n", token.Name, token.ID, token.CreatedAt.Local().Format(time.DateTime), tokenExpiry(token), lastUsed)
+	}
+	return nil
+}
+
+func revokeToken(s *State, args []string, user database.User) error {
+	if len(args) == 0 {
+		fmt.Println("Token id is required")
+		os.Exit(1)
+	}
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		fmt.Printf("Invalid token id %q\n", args[0])
+		os.Exit(1)
+	}
+	params := database.RevokeApiTokenParams{ID: id, UserID: user.ID}
+	revoked, err := s.Db.RevokeApiToken(context.Background(), params)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+	if revoked == 0 {
+		fmt.Printf("%s has no token %s\n", user.Name, id)
+		os.Exit(1)
+	}
+	fmt.Printf("Revoked token %s\n", id)
+	return nil
+}
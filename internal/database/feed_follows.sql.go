@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,10 +23,10 @@ WITH inserted_feed_follow AS (
         $4,
         $5
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, category
 )
 SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.category,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -47,6 +48,7 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 	FeedName  string
 	UserName  string
 }
@@ -66,6 +68,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Category,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -74,9 +77,19 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
-    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.category,
     feeds.name AS feed_name,
-    users.name AS user_name
+    feeds.url AS feed_url,
+    feeds.site_url AS feed_site_url,
+    users.name AS user_name,
+    (
+        SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id = feed_follows.feed_id
+          AND NOT EXISTS (
+            SELECT 1 FROM post_reads
+            WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+          )
+    ) AS unread_count
 FROM feed_follows
 INNER JOIN users ON users.id=feed_follows.user_id
 INNER JOIN feeds ON feeds.id=feed_follows.feed_id
@@ -84,13 +97,17 @@ WHERE users.name = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	Category    sql.NullString
+	FeedName    string
+	FeedUrl     string
+	FeedSiteUrl sql.NullString
+	UserName    string
+	UnreadCount int64
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]GetFeedFollowsForUserRow, error) {
@@ -108,8 +125,12 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.Category,
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedSiteUrl,
 			&i.UserName,
+			&i.UnreadCount,
 		); err != nil {
 			return nil, err
 		}
@@ -124,6 +145,22 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 	return items, nil
 }
 
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows SET feed_id = $1
+WHERE feed_follows.feed_id = $2
+  AND feed_follows.user_id NOT IN (SELECT kept.user_id FROM feed_follows AS kept WHERE kept.feed_id = $1)
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
 const removeFeedFollow = `-- name: RemoveFeedFollow :exec
 DELETE FROM feed_follows
 USING users, feeds
@@ -142,3 +179,25 @@ func (q *Queries) RemoveFeedFollow(ctx context.Context, arg RemoveFeedFollowPara
 	_, err := q.db.ExecContext(ctx, removeFeedFollow, arg.ID, arg.Url)
 	return err
 }
+
+const setFeedFollowCategory = `-- name: SetFeedFollowCategory :exec
+UPDATE feed_follows SET category = $3, updated_at = $4
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowCategoryParams struct {
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	Category  sql.NullString
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetFeedFollowCategory(ctx context.Context, arg SetFeedFollowCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFollowCategory,
+		arg.UserID,
+		arg.FeedID,
+		arg.Category,
+		arg.UpdatedAt,
+	)
+	return err
+}
@@ -13,6 +13,67 @@ import (
 	"github.com/google/uuid"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds SET last_fetched_at = $1, updated_at = $1, next_fetch_at = $2
+WHERE feeds.id IN (
+    SELECT due.id FROM feeds AS due
+    WHERE due.disabled_at IS NULL AND (due.next_fetch_at IS NULL OR due.next_fetch_at <= $1)
+    ORDER BY due.next_fetch_at NULLS FIRST, due.last_fetched_at NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, format, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_status, last_success_at, disabled_at, site_url, retention_keep_posts, retention_keep_days
+`
+
+type ClaimFeedsToFetchParams struct {
+	Now        sql.NullTime
+	LeaseUntil sql.NullTime
+	MaxResults int32
+}
+
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.Now, arg.LeaseUntil, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Format,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastStatus,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.SiteUrl,
+			&i.RetentionKeepPosts,
+			&i.RetentionKeepDays,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -23,7 +84,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, format, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_status, last_success_at, disabled_at, site_url, retention_keep_posts, retention_keep_days
 `
 
 type CreateFeedParams struct {
@@ -53,10 +114,31 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Format,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastStatus,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.SiteUrl,
+		&i.RetentionKeepPosts,
+		&i.RetentionKeepDays,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE feeds.id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
 const deleteOrphanedFeeds = `-- name: DeleteOrphanedFeeds :exec
 DELETE FROM feeds WHERE user_id IS NULL
 `
@@ -66,8 +148,80 @@ func (q *Queries) DeleteOrphanedFeeds(ctx context.Context) error {
 	return err
 }
 
+const disableFeed = `-- name: DisableFeed :exec
+UPDATE feeds SET disabled_at = $2 WHERE feeds.id = $1
+`
+
+type DisableFeedParams struct {
+	ID         uuid.UUID
+	DisabledAt sql.NullTime
+}
+
+func (q *Queries) DisableFeed(ctx context.Context, arg DisableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, disableFeed, arg.ID, arg.DisabledAt)
+	return err
+}
+
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled_at = NULL, consecutive_failures = 0, next_fetch_at = NULL
+WHERE feeds.id = $1
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, id)
+	return err
+}
+
+const getAllFeeds = `-- name: GetAllFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, format, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_status, last_success_at, disabled_at, site_url, retention_keep_posts, retention_keep_days FROM feeds ORDER BY name
+`
+
+func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Format,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastStatus,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.SiteUrl,
+			&i.RetentionKeepPosts,
+			&i.RetentionKeepDays,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeed = `-- name: GetFeed :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, format, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_status, last_success_at, disabled_at, site_url, retention_keep_posts, retention_keep_days FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
@@ -81,18 +235,68 @@ func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Format,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastStatus,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.SiteUrl,
+		&i.RetentionKeepPosts,
+		&i.RetentionKeepDays,
+	)
+	return i, err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, format, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_status, last_success_at, disabled_at, site_url, retention_keep_posts, retention_keep_days FROM feeds WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Format,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastStatus,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.SiteUrl,
+		&i.RetentionKeepPosts,
+		&i.RetentionKeepDays,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT feeds.name, feeds.url, users.name AS username FROM feeds INNER JOIN users ON feeds.user_id=users.id
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.site_url, feeds.user_id, feeds.last_fetched_at, users.name AS username
+FROM feeds INNER JOIN users ON feeds.user_id=users.id
 `
 
 type GetFeedsRow struct {
-	Name     string
-	Url      string
-	Username string
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Name          string
+	Url           string
+	SiteUrl       sql.NullString
+	UserID        uuid.UUID
+	LastFetchedAt sql.NullTime
+	Username      string
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -104,7 +308,17 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.Username); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.SiteUrl,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Username,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -118,23 +332,53 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds ORDER BY last_fetched_at NULLS FIRST LIMIT 1
+const getUnhealthyFeeds = `-- name: GetUnhealthyFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, format, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_status, last_success_at, disabled_at, site_url, retention_keep_posts, retention_keep_days FROM feeds
+WHERE consecutive_failures > 0 OR disabled_at IS NOT NULL
+ORDER BY disabled_at NULLS LAST, consecutive_failures DESC, name
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+func (q *Queries) GetUnhealthyFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getUnhealthyFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Format,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastStatus,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.SiteUrl,
+			&i.RetentionKeepPosts,
+			&i.RetentionKeepDays,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
@@ -150,3 +394,133 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.ID, arg.LastFetchedAt)
 	return err
 }
+
+const recordFeedFailure = `-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1, last_error = $2, last_status = $3
+WHERE feeds.id = $1
+RETURNING consecutive_failures
+`
+
+type RecordFeedFailureParams struct {
+	ID         uuid.UUID
+	LastError  sql.NullString
+	LastStatus sql.NullInt32
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure, arg.ID, arg.LastError, arg.LastStatus)
+	var consecutive_failures int32
+	err := row.Scan(&consecutive_failures)
+	return consecutive_failures, err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0, last_error = NULL, last_status = $2, last_success_at = $3
+WHERE feeds.id = $1
+`
+
+type RecordFeedSuccessParams struct {
+	ID            uuid.UUID
+	LastStatus    sql.NullInt32
+	LastSuccessAt sql.NullTime
+}
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.ID, arg.LastStatus, arg.LastSuccessAt)
+	return err
+}
+
+const setFeedCacheHeaders = `-- name: SetFeedCacheHeaders :exec
+UPDATE feeds SET etag = $2, last_modified = $3 WHERE feeds.id = $1
+`
+
+type SetFeedCacheHeadersParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) SetFeedCacheHeaders(ctx context.Context, arg SetFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheHeaders, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const setFeedFormat = `-- name: SetFeedFormat :exec
+UPDATE feeds SET format = $2 WHERE feeds.id = $1
+`
+
+type SetFeedFormatParams struct {
+	ID     uuid.UUID
+	Format sql.NullString
+}
+
+func (q *Queries) SetFeedFormat(ctx context.Context, arg SetFeedFormatParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFormat, arg.ID, arg.Format)
+	return err
+}
+
+const setFeedNextFetch = `-- name: SetFeedNextFetch :exec
+UPDATE feeds SET next_fetch_at = $2 WHERE feeds.id = $1
+`
+
+type SetFeedNextFetchParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) SetFeedNextFetch(ctx context.Context, arg SetFeedNextFetchParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetch, arg.ID, arg.NextFetchAt)
+	return err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :exec
+UPDATE feeds SET retention_keep_posts = $2, retention_keep_days = $3, updated_at = $4 WHERE feeds.id = $1
+`
+
+type SetFeedRetentionParams struct {
+	ID                 uuid.UUID
+	RetentionKeepPosts sql.NullInt32
+	RetentionKeepDays  sql.NullInt32
+	UpdatedAt          time.Time
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRetention,
+		arg.ID,
+		arg.RetentionKeepPosts,
+		arg.RetentionKeepDays,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const setFeedSiteUrl = `-- name: SetFeedSiteUrl :exec
+UPDATE feeds SET site_url = $2 WHERE feeds.id = $1
+`
+
+type SetFeedSiteUrlParams struct {
+	ID      uuid.UUID
+	SiteUrl sql.NullString
+}
+
+func (q *Queries) SetFeedSiteUrl(ctx context.Context, arg SetFeedSiteUrlParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSiteUrl, arg.ID, arg.SiteUrl)
+	return err
+}
+
+const updateFeedUrl = `-- name: UpdateFeedUrl :exec
+UPDATE feeds SET url = $2, updated_at = $3 WHERE feeds.id = $1
+`
+
+type UpdateFeedUrlParams struct {
+	ID        uuid.UUID
+	Url       string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateFeedUrl(ctx context.Context, arg UpdateFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedUrl, arg.ID, arg.Url, arg.UpdatedAt)
+	return err
+}
@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one goose formatted file from sql/schema (Postgres) or
+// sql/sqlite/schema (SQLite). Versions are tracked in goose_db_version so
+// databases migrated with the goose CLI keep working.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+var ErrNoMigrationApplied = errors.New("no migration has been applied")
+
+// Migrator applies the migrations embedded in the binary.
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, dialect string, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys, MigrationsDir(dialect))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+func MigrationsDir(dialect string) string {
+	if dialect == DialectSQLite {
+		return "sql/sqlite/schema"
+	}
+	return "sql/schema"
+}
+
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	migrations := []Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has no numeric version prefix", entry.Name())
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		up, down := splitMigration(string(data))
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    entry.Name(),
+			Up:      up,
+			Down:    down,
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func splitMigration(data string) (string, string) {
+	_, rest, _ := strings.Cut(data, "-- +goose Up")
+	up, down, _ := strings.Cut(rest, "-- +goose Down")
+	return strings.TrimSpace(up), strings.TrimSpace(down)
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []MigrationStatus{}
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Pending lists the migrations the database is missing.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration, each one in its own transaction.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for _, migration := range pending {
+		err = m.run(ctx, migration, true)
+		if err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return Migration{}, err
+	}
+	for i := len(statuses) - 1; i >= 0; i-- {
+		if statuses[i].Applied {
+			return statuses[i].Migration, m.run(ctx, statuses[i].Migration, false)
+		}
+	}
+	return Migration{}, ErrNoMigrationApplied
+}
+
+// Redo rolls back the most recently applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) (Migration, error) {
+	migration, err := m.Down(ctx)
+	if err != nil {
+		return migration, err
+	}
+	return migration, m.run(ctx, migration, true)
+}
+
+func (m *Migrator) run(ctx context.Context, migration Migration, up bool) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	statements := migration.Up
+	if !up {
+		statements = migration.Down
+	}
+	if statements != "" {
+		_, err = tx.ExecContext(ctx, statements)
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", migration.Name, err)
+		}
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, m.query("INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, $2)"), migration.Version, true)
+	} else {
+		_, err = tx.ExecContext(ctx, m.query("DELETE FROM goose_db_version WHERE version_id = $1"), migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// appliedVersions reads the version table, creating it on first use. The
+// newest row of a version decides, goose versions before v3 recorded
+// rollbacks as rows with is_applied = false.
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]time.Time, error) {
+	err := m.ensureVersionTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, "SELECT version_id, is_applied, tstamp FROM goose_db_version ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int64]time.Time{}
+	seen := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var appliedAt sql.NullTime
+		if err := rows.Scan(&version, &isApplied, &appliedAt); err != nil {
+			return nil, err
+		}
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+		if isApplied && version > 0 {
+			applied[version] = appliedAt.Time
+		}
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) ensureVersionTable(ctx context.Context) error {
+	create := `CREATE TABLE IF NOT EXISTS goose_db_version (
+  id serial PRIMARY KEY,
+  version_id bigint NOT NULL,
+  is_applied boolean NOT NULL,
+  tstamp timestamp NULL DEFAULT now()
+)`
+	if m.dialect == DialectSQLite {
+		create = `CREATE TABLE IF NOT EXISTS goose_db_version (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  version_id INTEGER NOT NULL,
+  is_applied INTEGER NOT NULL,
+  tstamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+	}
+	_, err := m.db.ExecContext(ctx, create)
+	return err
+}
+
+func (m *Migrator) query(query string) string {
+	if m.dialect == DialectSQLite {
+		return sqliteQuery(query)
+	}
+	return query
+}
@@ -11,14 +11,45 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Format              sql.NullString
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	NextFetchAt         sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastStatus          sql.NullInt32
+	LastSuccessAt       sql.NullTime
+	DisabledAt          sql.NullTime
+	SiteUrl             sql.NullString
+	RetentionKeepPosts  sql.NullInt32
+	RetentionKeepDays   sql.NullInt32
+}
+
+type FeedEvent struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.NullUUID
+	FeedUrl   string
+	Kind      string
+	Detail    sql.NullString
 }
 
 type FeedFollow struct {
@@ -27,6 +58,30 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
+}
+
+type FeedIcon struct {
+	FeedID    uuid.UUID
+	MimeType  string
+	Data      []byte
+	FetchedAt time.Time
+}
+
+type FeverFeed struct {
+	ID     int32
+	FeedID uuid.UUID
+}
+
+type FeverItem struct {
+	ID     int64
+	PostID uuid.UUID
+}
+
+type FeverKey struct {
+	UserID    uuid.UUID
+	KeyHash   string
+	CreatedAt time.Time
 }
 
 type Post struct {
@@ -38,11 +93,40 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Guid        string
+	ContentHash sql.NullString
 }
 
-type User struct {
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostSearch struct {
+	PostID   uuid.UUID
+	Document interface{}
+}
+
+type SavedPost struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+	Note      sql.NullString
+}
+
+type Session struct {
 	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ExpiresAt time.Time
+}
+
+type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
 }
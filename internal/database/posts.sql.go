@@ -13,22 +13,45 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id,created_at,updated_at,title,url,description,published_at, feed_id)
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5,
-    $6,
-    $7,
-    $8
-)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const browsePostsByFetched = `-- name: BrowsePostsByFetched :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content_hash,
+    feeds.name AS feed_name,
+    feed_follows.category,
+    post_reads.read_at
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+LEFT JOIN post_reads ON post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+WHERE feed_follows.user_id = $1
+  AND (posts.feed_id = $2 OR $2 IS NULL)
+  AND (feed_follows.category = $3 OR substr(feed_follows.category, 1, length($3) + 1) = $3 || '/' OR $3 IS NULL)
+  AND (posts.published_at >= $4 OR $4 IS NULL)
+  AND (posts.published_at < $5 OR $5 IS NULL)
+  AND (CAST($6 AS boolean) = FALSE OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+  ))
+  AND (posts.created_at < $7
+    OR (posts.created_at = $7 AND posts.id < $8)
+    OR $7 IS NULL)
+ORDER BY posts.created_at DESC, posts.id DESC
+LIMIT $9
 `
 
-type CreatePostParams struct {
+type BrowsePostsByFetchedParams struct {
+	UserID     uuid.UUID
+	FeedID     uuid.NullUUID
+	Category   sql.NullString
+	Since      sql.NullTime
+	Until      sql.NullTime
+	UnreadOnly bool
+	AfterTime  sql.NullTime
+	AfterID    uuid.NullUUID
+	MaxResults int32
+}
+
+type BrowsePostsByFetchedRow struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -37,19 +60,192 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Guid        string
+	ContentHash sql.NullString
+	FeedName    string
+	Category    sql.NullString
+	ReadAt      sql.NullTime
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
+func (q *Queries) BrowsePostsByFetched(ctx context.Context, arg BrowsePostsByFetchedParams) ([]BrowsePostsByFetchedRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsByFetched,
+		arg.UserID,
 		arg.FeedID,
+		arg.Category,
+		arg.Since,
+		arg.Until,
+		arg.UnreadOnly,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.MaxResults,
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsByFetchedRow
+	for rows.Next() {
+		var i BrowsePostsByFetchedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.FeedName,
+			&i.Category,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const browsePostsByPublished = `-- name: BrowsePostsByPublished :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content_hash,
+    feeds.name AS feed_name,
+    feed_follows.category,
+    post_reads.read_at
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+LEFT JOIN post_reads ON post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+WHERE feed_follows.user_id = $1
+  AND (posts.feed_id = $2 OR $2 IS NULL)
+  AND (feed_follows.category = $3 OR substr(feed_follows.category, 1, length($3) + 1) = $3 || '/' OR $3 IS NULL)
+  AND (posts.published_at >= $4 OR $4 IS NULL)
+  AND (posts.published_at < $5 OR $5 IS NULL)
+  AND (CAST($6 AS boolean) = FALSE OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+  ))
+  AND (posts.published_at < $7
+    OR (posts.published_at = $7 AND posts.id < $8)
+    OR $7 IS NULL)
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT $9
+`
+
+type BrowsePostsByPublishedParams struct {
+	UserID     uuid.UUID
+	FeedID     uuid.NullUUID
+	Category   sql.NullString
+	Since      sql.NullTime
+	Until      sql.NullTime
+	UnreadOnly bool
+	AfterTime  sql.NullTime
+	AfterID    uuid.NullUUID
+	MaxResults int32
+}
+
+type BrowsePostsByPublishedRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Guid        string
+	ContentHash sql.NullString
+	FeedName    string
+	Category    sql.NullString
+	ReadAt      sql.NullTime
+}
+
+func (q *Queries) BrowsePostsByPublished(ctx context.Context, arg BrowsePostsByPublishedParams) ([]BrowsePostsByPublishedRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsByPublished,
+		arg.UserID,
+		arg.FeedID,
+		arg.Category,
+		arg.Since,
+		arg.Until,
+		arg.UnreadOnly,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsByPublishedRow
+	for rows.Next() {
+		var i BrowsePostsByPublishedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.FeedName,
+			&i.Category,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getKeepPostsCutoff = `-- name: GetKeepPostsCutoff :one
+SELECT published_at, created_at FROM posts
+WHERE feed_id = $1
+ORDER BY coalesce(published_at, created_at) DESC
+LIMIT 1 OFFSET $2
+`
+
+type GetKeepPostsCutoffParams struct {
+	FeedID uuid.UUID
+	Offset int32
+}
+
+type GetKeepPostsCutoffRow struct {
+	PublishedAt sql.NullTime
+	CreatedAt   time.Time
+}
+
+// Ranks posts the same way PrunePosts does for keep_posts.
+func (q *Queries) GetKeepPostsCutoff(ctx context.Context, arg GetKeepPostsCutoffParams) (GetKeepPostsCutoffRow, error) {
+	row := q.db.QueryRowContext(ctx, getKeepPostsCutoff, arg.FeedID, arg.Offset)
+	var i GetKeepPostsCutoffRow
+	err := row.Scan(&i.PublishedAt, &i.CreatedAt)
+	return i, err
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content_hash FROM posts WHERE id = $1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -60,32 +256,246 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Guid,
+		&i.ContentHash,
 	)
 	return i, err
 }
 
-const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id FROM posts 
-INNER JOIN feed_follows ON posts.feed_id=feed_follows.feed_id 
-WHERE feed_follows.user_id=$1 
-ORDER BY posts.published_at DESC NULLS FIRST
+const getPrunablePosts = `-- name: GetPrunablePosts :many
+SELECT posts.id, posts.title, posts.published_at FROM posts
+WHERE posts.feed_id = $1
+  AND (
+    (CAST($2 AS integer) > 0 AND posts.id NOT IN (
+        SELECT newest.id FROM posts newest
+        WHERE newest.feed_id = $1
+        ORDER BY coalesce(newest.published_at, newest.created_at) DESC
+        LIMIT $2
+    ))
+    OR coalesce(posts.published_at, posts.created_at) < $3
+  )
+  AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+  AND NOT (
+    coalesce(posts.published_at, posts.created_at) >= $4
+    AND EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+          AND NOT EXISTS (
+            SELECT 1 FROM post_reads
+            WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+          )
+    )
+  )
+ORDER BY coalesce(posts.published_at, posts.created_at)
+`
+
+type GetPrunablePostsParams struct {
+	FeedID          uuid.UUID
+	KeepPosts       int32
+	PublishedBefore sql.NullTime
+	UnreadSince     sql.NullTime
+}
+
+type GetPrunablePostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	PublishedAt sql.NullTime
+}
+
+func (q *Queries) GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePosts,
+		arg.FeedID,
+		arg.KeepPosts,
+		arg.PublishedBefore,
+		arg.UnreadSince,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPrunablePostsRow
+	for rows.Next() {
+		var i GetPrunablePostsRow
+		if err := rows.Scan(&i.ID, &i.Title, &i.PublishedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPublishTimes = `-- name: GetRecentPublishTimes :many
+SELECT published_at FROM posts
+WHERE feed_id = $1 AND published_at IS NOT NULL AND published_at <> created_at
+ORDER BY published_at DESC
 LIMIT $2
 `
 
-type GetPostsForUserParams struct {
-	UserID uuid.UUID
+type GetRecentPublishTimesParams struct {
+	FeedID uuid.UUID
 	Limit  int32
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+// Items without a date are stored with the fetch time, which is also their
+// created_at; those say nothing about how often the feed publishes.
+func (q *Queries) GetRecentPublishTimes(ctx context.Context, arg GetRecentPublishTimesParams) ([]sql.NullTime, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPublishTimes, arg.FeedID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []sql.NullTime
 	for rows.Next() {
-		var i Post
+		var published_at sql.NullTime
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts SET feed_id = $1
+WHERE posts.feed_id = $2
+  AND posts.guid NOT IN (SELECT kept.guid FROM posts AS kept WHERE kept.feed_id = $1)
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const prunePosts = `-- name: PrunePosts :execrows
+DELETE FROM posts
+WHERE posts.feed_id = $1
+  AND (
+    (CAST($2 AS integer) > 0 AND posts.id NOT IN (
+        SELECT newest.id FROM posts newest
+        WHERE newest.feed_id = $1
+        ORDER BY coalesce(newest.published_at, newest.created_at) DESC
+        LIMIT $2
+    ))
+    OR coalesce(posts.published_at, posts.created_at) < $3
+  )
+  AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+  AND NOT (
+    coalesce(posts.published_at, posts.created_at) >= $4
+    AND EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+          AND NOT EXISTS (
+            SELECT 1 FROM post_reads
+            WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+          )
+    )
+  )
+`
+
+type PrunePostsParams struct {
+	FeedID          uuid.UUID
+	KeepPosts       int32
+	PublishedBefore sql.NullTime
+	UnreadSince     sql.NullTime
+}
+
+func (q *Queries) PrunePosts(ctx context.Context, arg PrunePostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, prunePosts,
+		arg.FeedID,
+		arg.KeepPosts,
+		arg.PublishedBefore,
+		arg.UnreadSince,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content_hash,
+    feeds.name AS feed_name,
+    ts_rank(post_search.document, query) AS rank,
+    ts_headline(
+        'english',
+        regexp_replace(coalesce(nullif(posts.description, ''), posts.title), '<[^>]*>', ' ', 'g'),
+        query,
+        'StartSel=**, StopSel=**, MaxWords=30, MinWords=10, MaxFragments=2'
+    )::text AS snippet
+FROM post_search
+INNER JOIN posts ON posts.id = post_search.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id,
+    to_tsquery('english', $1) query
+WHERE post_search.document @@ query
+  AND ($2::boolean OR posts.feed_id IN (SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = $3))
+  AND ($4::uuid IS NULL OR posts.feed_id = $4)
+  AND ($5::timestamp IS NULL OR posts.published_at >= $5)
+  AND ($6::timestamp IS NULL OR posts.published_at < $6)
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT $7
+`
+
+type SearchPostsParams struct {
+	Query      string
+	AllFeeds   bool
+	UserID     uuid.UUID
+	FeedID     uuid.NullUUID
+	Since      sql.NullTime
+	Until      sql.NullTime
+	MaxResults int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Guid        string
+	ContentHash sql.NullString
+	FeedName    string
+	Rank        float32
+	Snippet     string
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Query,
+		arg.AllFeeds,
+		arg.UserID,
+		arg.FeedID,
+		arg.Since,
+		arg.Until,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -95,6 +505,11 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
@@ -108,3 +523,85 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (id,created_at,updated_at,title,url,description,published_at, feed_id, guid, content_hash)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
+)
+ON CONFLICT (feed_id, guid) DO UPDATE SET
+    -- posts stored before content_hash existed only get their hash filled in
+    updated_at = CASE WHEN posts.content_hash IS NULL THEN posts.updated_at ELSE EXCLUDED.updated_at END,
+    title = CASE WHEN posts.content_hash IS NULL THEN posts.title ELSE EXCLUDED.title END,
+    url = CASE WHEN posts.content_hash IS NULL THEN posts.url ELSE EXCLUDED.url END,
+    description = CASE WHEN posts.content_hash IS NULL THEN posts.description ELSE EXCLUDED.description END,
+    content_hash = EXCLUDED.content_hash
+WHERE posts.content_hash IS DISTINCT FROM EXCLUDED.content_hash
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content_hash, posts.updated_at = $3 AS changed
+`
+
+type UpsertPostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Guid        string
+	ContentHash sql.NullString
+}
+
+type UpsertPostRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Guid        string
+	ContentHash sql.NullString
+	Changed     bool
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (UpsertPostRow, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+		arg.ContentHash,
+	)
+	var i UpsertPostRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Changed,
+	)
+	return i, err
+}
@@ -0,0 +1,345 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver is go-sqlite3 with the functions the SQLite statements use.
+const sqliteDriver = "sqlite3_rss"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("search_rank", searchScore, true)
+		},
+	})
+}
+
+// SQLiteStore runs the sqlc generated queries against SQLite. Placeholders
+// and time values are translated by sqliteDB, the few statements relying on
+// Postgres only syntax are overridden below.
+type SQLiteStore struct {
+	*Queries
+}
+
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{Queries: New(sqliteDB{db: db})}
+}
+
+// sqliteDSN turns sqlite:///abs/path.db, sqlite://rel/path.db or a file:
+// URI into a go-sqlite3 DSN with foreign keys and a busy timeout enabled.
+func sqliteDSN(dbURL string) string {
+	path := dbURL
+	for _, prefix := range []string{"sqlite3://", "sqlite://", "sqlite3:", "sqlite:", "file:"} {
+		if strings.HasPrefix(strings.ToLower(path), prefix) {
+			path = path[len(prefix):]
+			break
+		}
+	}
+	path, query, _ := strings.Cut(path, "?")
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, rest)
+	}
+	params := []string{"_foreign_keys=on", "_busy_timeout=5000", "_journal_mode=WAL"}
+	if query != "" {
+		params = append([]string{query}, params...)
+	}
+	return "file:" + path + "?" + strings.Join(params, "&")
+}
+
+var postgresPlaceholder = regexp.MustCompile(`\$(\d+)`)
+
+// sqliteDB adapts the generated statements: $1 becomes ?1 and times are
+// stored in UTC so that comparing the stored text sorts chronologically.
+type sqliteDB struct {
+	db DBTX
+}
+
+func (s sqliteDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.db.ExecContext(ctx, sqliteQuery(query), sqliteArgs(args)...)
+}
+
+func (s sqliteDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return s.db.PrepareContext(ctx, sqliteQuery(query))
+}
+
+func (s sqliteDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, sqliteQuery(query), sqliteArgs(args)...)
+}
+
+func (s sqliteDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRowContext(ctx, sqliteQuery(query), sqliteArgs(args)...)
+}
+
+func sqliteQuery(query string) string {
+	return postgresPlaceholder.ReplaceAllString(query, "?$1")
+}
+
+func sqliteArgs(args []interface{}) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case time.Time:
+			converted[i] = value.UTC()
+		case sql.NullTime:
+			if value.Valid {
+				value.Time = value.Time.UTC()
+			}
+			converted[i] = value
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
+
+// SQLite has no row locks, writers are serialized instead, so the claim is
+// atomic without FOR UPDATE SKIP LOCKED.
+const sqliteClaimFeedsToFetch = `
+UPDATE feeds SET last_fetched_at = $1, updated_at = $1, next_fetch_at = $2
+WHERE feeds.id IN (
+    SELECT due.id FROM feeds AS due
+    WHERE due.disabled_at IS NULL AND (due.next_fetch_at IS NULL OR due.next_fetch_at <= $1)
+    ORDER BY due.next_fetch_at NULLS FIRST, due.last_fetched_at NULLS FIRST
+    LIMIT $3
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, format, etag, last_modified, next_fetch_at, consecutive_failures, last_error, last_status, last_success_at, disabled_at, site_url, retention_keep_posts, retention_keep_days
+`
+
+func (s *SQLiteStore) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := s.db.QueryContext(ctx, sqliteClaimFeedsToFetch, arg.Now, arg.LeaseUntil, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Format,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastStatus,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.SiteUrl,
+			&i.RetentionKeepPosts,
+			&i.RetentionKeepDays,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return items, rows.Err()
+}
+
+// SQLite does not allow INSERT inside a WITH clause, so the follow is
+// inserted first and read back with the names joined in.
+const sqliteCreateFeedFollow = `
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING
+    id, created_at, updated_at, user_id, feed_id, category,
+    (SELECT name FROM feeds WHERE feeds.id = feed_id) AS feed_name,
+    (SELECT name FROM users WHERE users.id = user_id) AS user_name
+`
+
+func (s *SQLiteStore) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
+	row := s.db.QueryRowContext(ctx, sqliteCreateFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	var i CreateFeedFollowRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Category,
+		&i.FeedName,
+		&i.UserName,
+	)
+	return i, err
+}
+
+// SQLite has no DELETE ... USING.
+const sqliteRemoveFeedFollow = `
+DELETE FROM feed_follows
+WHERE user_id = $1
+  AND feed_id IN (SELECT id FROM feeds WHERE url = $2)
+`
+
+func (s *SQLiteStore) RemoveFeedFollow(ctx context.Context, arg RemoveFeedFollowParams) error {
+	_, err := s.db.ExecContext(ctx, sqliteRemoveFeedFollow, arg.ID, arg.Url)
+	return err
+}
+
+// SQLite has no casts to uuid or timestamp, the optional filters compare the
+// parameters to NULL directly.
+const sqliteMarkPostsRead = `
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, $1
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $2
+  AND ($3 IS NULL OR posts.feed_id = $3)
+  AND ($4 IS NULL OR posts.published_at < $4)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (s *SQLiteStore) MarkPostsRead(ctx context.Context, arg MarkPostsReadParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, sqliteMarkPostsRead,
+		arg.ReadAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// The FTS4 table replaces post_search's tsvector, its docid is the rowid of
+// the post. SQLite has no ranking function built in, search_rank scores the
+// matchinfo of a row with searchScore.
+const sqliteSearchPosts = `
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content_hash,
+    feeds.name AS feed_name,
+    search_rank(matchinfo(post_search, 'pcnx')) AS rank,
+    snippet(post_search, '**', '**', '…', -1, 24) AS snippet
+FROM post_search
+INNER JOIN posts ON posts.rowid = post_search.docid
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE post_search MATCH $1
+  AND ($2 OR posts.feed_id IN (SELECT feed_id FROM feed_follows WHERE user_id = $3))
+  AND ($4 IS NULL OR posts.feed_id = $4)
+  AND ($5 IS NULL OR posts.published_at >= $5)
+  AND ($6 IS NULL OR posts.published_at < $6)
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT $7
+`
+
+// searchColumnWeights weight the post_search columns post_id, title and
+// description like the A and B weights of the Postgres document.
+var searchColumnWeights = []float64{0, 2, 1}
+
+func (s *SQLiteStore) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := s.db.QueryContext(ctx, sqliteSearchPosts,
+		sqliteMatchQuery(arg.Query),
+		arg.AllFeeds,
+		arg.UserID,
+		arg.FeedID,
+		arg.Since,
+		arg.Until,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// searchScore is a tf-idf score over the 'pcnx' matchinfo blob: the phrase
+// and column counts, the number of rows, then for every phrase and column
+// the hits in this row, in all rows and the number of rows with a hit.
+func searchScore(matchInfo []byte) float64 {
+	values := make([]uint32, len(matchInfo)/4)
+	for i := range values {
+		values[i] = binary.NativeEndian.Uint32(matchInfo[i*4:])
+	}
+	if len(values) < 3 {
+		return 0
+	}
+	phrases, columns, total := int(values[0]), int(values[1]), float64(values[2])
+	score := 0.0
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < columns && c < len(searchColumnWeights); c++ {
+			offset := 3 + 3*(p*columns+c)
+			if offset+2 >= len(values) || values[offset+2] == 0 {
+				continue
+			}
+			idf := math.Log(1 + total/float64(values[offset+2]))
+			score += searchColumnWeights[c] * float64(values[offset]) * idf
+		}
+	}
+	return score
+}
+
+// sqliteMatchQuery turns the to_tsquery expression built for Postgres into
+// FTS4 syntax: "a & b:* & (c <-> d)" becomes `a b* "c d"`.
+func sqliteMatchQuery(tsquery string) string {
+	terms := []string{}
+	for _, term := range strings.Split(tsquery, " & ") {
+		term = strings.TrimSpace(term)
+		if phrase, found := strings.CutPrefix(term, "("); found {
+			phrase = strings.TrimSuffix(phrase, ")")
+			phrase = strings.ReplaceAll(phrase, " <-> ", " ")
+			terms = append(terms, `"`+strings.ReplaceAll(phrase, ":*", "*")+`"`)
+			continue
+		}
+		if word, found := strings.CutSuffix(term, ":*"); found {
+			term = word + "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store is the persistence layer used by the command handlers. The sqlc
+// generated Queries implement it for Postgres, SQLiteStore for SQLite.
+type Store interface {
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	GetUser(ctx context.Context, name string) (User, error)
+	GetUsers(ctx context.Context) ([]User, error)
+	DeleteUsers(ctx context.Context) error
+	SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error
+
+	CreateSession(ctx context.Context, arg CreateSessionParams) error
+	GetUserBySession(ctx context.Context, arg GetUserBySessionParams) (User, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+	DeleteSessionsForUser(ctx context.Context, userID uuid.UUID) error
+	DeleteExpiredSessions(ctx context.Context, expiresAt time.Time) error
+
+	CreateApiToken(ctx context.Context, arg CreateApiTokenParams) (ApiToken, error)
+	GetApiTokensForUser(ctx context.Context, userID uuid.UUID) ([]ApiToken, error)
+	GetUserByApiToken(ctx context.Context, arg GetUserByApiTokenParams) (GetUserByApiTokenRow, error)
+	TouchApiToken(ctx context.Context, arg TouchApiTokenParams) error
+	RevokeApiToken(ctx context.Context, arg RevokeApiTokenParams) (int64, error)
+
+	CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error)
+	GetFeed(ctx context.Context, url string) (Feed, error)
+	GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error)
+	GetFeeds(ctx context.Context) ([]GetFeedsRow, error)
+	GetAllFeeds(ctx context.Context) ([]Feed, error)
+	DeleteFeed(ctx context.Context, id uuid.UUID) error
+	DeleteOrphanedFeeds(ctx context.Context) error
+	ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error)
+	SetFeedFormat(ctx context.Context, arg SetFeedFormatParams) error
+	SetFeedSiteUrl(ctx context.Context, arg SetFeedSiteUrlParams) error
+	SetFeedCacheHeaders(ctx context.Context, arg SetFeedCacheHeadersParams) error
+	SetFeedNextFetch(ctx context.Context, arg SetFeedNextFetchParams) error
+	UpdateFeedUrl(ctx context.Context, arg UpdateFeedUrlParams) error
+	RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error
+	RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (int32, error)
+	DisableFeed(ctx context.Context, arg DisableFeedParams) error
+	EnableFeed(ctx context.Context, id uuid.UUID) error
+	GetUnhealthyFeeds(ctx context.Context) ([]Feed, error)
+	SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error
+	GetFeedIcon(ctx context.Context, feedID uuid.UUID) (FeedIcon, error)
+	UpsertFeedIcon(ctx context.Context, arg UpsertFeedIconParams) error
+
+	CreateFeedEvent(ctx context.Context, arg CreateFeedEventParams) error
+	GetFeedEvents(ctx context.Context, limit int32) ([]FeedEvent, error)
+
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	GetFeedFollowsForUser(ctx context.Context, name string) ([]GetFeedFollowsForUserRow, error)
+	RemoveFeedFollow(ctx context.Context, arg RemoveFeedFollowParams) error
+	MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error
+	SetFeedFollowCategory(ctx context.Context, arg SetFeedFollowCategoryParams) error
+
+	UpsertPost(ctx context.Context, arg UpsertPostParams) (UpsertPostRow, error)
+	BrowsePostsByPublished(ctx context.Context, arg BrowsePostsByPublishedParams) ([]BrowsePostsByPublishedRow, error)
+	BrowsePostsByFetched(ctx context.Context, arg BrowsePostsByFetchedParams) ([]BrowsePostsByFetchedRow, error)
+	GetRecentPublishTimes(ctx context.Context, arg GetRecentPublishTimesParams) ([]sql.NullTime, error)
+	GetKeepPostsCutoff(ctx context.Context, arg GetKeepPostsCutoffParams) (GetKeepPostsCutoffRow, error)
+	MovePosts(ctx context.Context, arg MovePostsParams) error
+	GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error)
+	PrunePosts(ctx context.Context, arg PrunePostsParams) (int64, error)
+	GetPost(ctx context.Context, id uuid.UUID) (Post, error)
+	SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error)
+
+	MarkPostRead(ctx context.Context, arg MarkPostReadParams) error
+	MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) (int64, error)
+	MarkPostsRead(ctx context.Context, arg MarkPostsReadParams) (int64, error)
+
+	SavePost(ctx context.Context, arg SavePostParams) error
+	UnsavePost(ctx context.Context, arg UnsavePostParams) (int64, error)
+	GetSavedPostsForUser(ctx context.Context, userID uuid.UUID) ([]GetSavedPostsForUserRow, error)
+	MergeSavedPostNotes(ctx context.Context, arg MergeSavedPostNotesParams) error
+	MoveSavedPosts(ctx context.Context, arg MoveSavedPostsParams) error
+
+	SetFeverKey(ctx context.Context, arg SetFeverKeyParams) error
+	DeleteFeverKey(ctx context.Context, userID uuid.UUID) (int64, error)
+	GetUserByFeverKey(ctx context.Context, keyHash string) (User, error)
+	GetFeverFeeds(ctx context.Context, userID uuid.UUID) ([]GetFeverFeedsRow, error)
+	GetFeverFavicons(ctx context.Context, userID uuid.UUID) ([]GetFeverFaviconsRow, error)
+	GetFeverItemsAfter(ctx context.Context, arg GetFeverItemsAfterParams) ([]GetFeverItemsAfterRow, error)
+	GetFeverItemsBefore(ctx context.Context, arg GetFeverItemsBeforeParams) ([]GetFeverItemsBeforeRow, error)
+	GetFeverItem(ctx context.Context, arg GetFeverItemParams) (GetFeverItemRow, error)
+	CountFeverItems(ctx context.Context, userID uuid.UUID) (int64, error)
+	GetUnreadFeverItemIDs(ctx context.Context, userID uuid.UUID) ([]int64, error)
+	GetSavedFeverItemIDs(ctx context.Context, userID uuid.UUID) ([]int64, error)
+	MarkFeverItemsRead(ctx context.Context, arg MarkFeverItemsReadParams) (int64, error)
+}
+
+var (
+	_ Store = (*Queries)(nil)
+	_ Store = (*SQLiteStore)(nil)
+)
+
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// Dialect picks the backend from the scheme of the configured db_url:
+// postgres:// or postgresql:// for Postgres, sqlite:// or file: for SQLite.
+// A db_url without a scheme is a lib/pq keyword string such as
+// "host=localhost user=rss" and goes to Postgres.
+func Dialect(dbURL string) (string, error) {
+	scheme, _, found := strings.Cut(dbURL, ":")
+	if !found || strings.ContainsAny(scheme, "= \t") {
+		return DialectPostgres, nil
+	}
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return DialectPostgres, nil
+	case "sqlite", "sqlite3", "file":
+		return DialectSQLite, nil
+	}
+	return "", fmt.Errorf("unsupported db_url scheme %q", scheme)
+}
+
+// Open connects to the database behind dbURL. The Postgres driver has to be
+// registered by the caller, the SQLite one is registered by this package.
+func Open(dbURL string) (Store, *sql.DB, error) {
+	dialect, err := Dialect(dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dialect == DialectSQLite {
+		db, err := sql.Open(sqliteDriver, sqliteDSN(dbURL))
+		if err != nil {
+			return nil, nil, err
+		}
+		return NewSQLiteStore(db), db, nil
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return New(db), db, nil
+}
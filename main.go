@@ -1,7 +1,7 @@
 package main
 
 import (
-	"database/sql"
+	"embed"
 	"fmt"
 	"os"
 	"rss-aggregator/internal/config"
@@ -10,38 +10,70 @@ import (
 	_ "github.com/lib/pq"
 )
 
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var migrationFiles embed.FS
+
 func main() {
 	commands := config.Commands{
 		Map: make(map[string]func(*config.State, config.CommandInput) error),
 	}
 	commands.Register("login", config.HandlerLogin)
 	commands.Register("reset", config.HandlerReset)
+	commands.Register("migrate", config.HandlerMigrate)
 	commands.Register("register", config.HandlerRegister)
 	commands.Register("users", config.HandlerListUsers)
 	commands.Register("agg", config.HandlerAgg)
 	commands.Register("feeds", config.HandlerListFeeds)
+	commands.Register("feedstatus", config.HandlerFeedStatus)
+	commands.Register("retention", config.HandlerRetention)
+	commands.Register("prune", config.HandlerPrune)
+	commands.Register("serve", config.HandlerServe)
 	commands.Register("addfeed", config.MiddlewareLoggedIn(config.HandlerAddFeed))
 	commands.Register("follow", config.MiddlewareLoggedIn(config.HandlerFollow))
 	commands.Register("unfollow", config.MiddlewareLoggedIn(config.HandlerUnfollow))
 	commands.Register("following", config.MiddlewareLoggedIn(config.HandlerFollowing))
 	commands.Register("browse", config.MiddlewareLoggedIn(config.HandlerBrowse))
+	commands.Register("read", config.MiddlewareLoggedIn(config.HandlerRead))
+	commands.Register("unread", config.MiddlewareLoggedIn(config.HandlerUnread))
+	commands.Register("markread", config.MiddlewareLoggedIn(config.HandlerMarkRead))
+	commands.Register("search", config.MiddlewareLoggedIn(config.HandlerSearch))
+	commands.Register("star", config.MiddlewareLoggedIn(config.HandlerStar))
+	commands.Register("unstar", config.MiddlewareLoggedIn(config.HandlerUnstar))
+	commands.Register("starred", config.MiddlewareLoggedIn(config.HandlerStarred))
+	commands.Register("import", config.MiddlewareLoggedIn(config.HandlerImport))
+	commands.Register("export", config.MiddlewareLoggedIn(config.HandlerExport))
+	commands.Register("token", config.MiddlewareLoggedIn(config.HandlerToken))
+	commands.Register("passwd", config.MiddlewareLoggedIn(config.HandlerPasswd))
+	commands.Register("fever", config.MiddlewareLoggedIn(config.HandlerFever))
 	conf := config.Read()
-	db, err := sql.Open("postgres", conf.DBurl)
+	store, db, err := database.Open(conf.DBurl)
 	if err != nil {
 		fmt.Printf("Error when opening db:\t %s\n", err)
+		os.Exit(1)
+	}
+	dialect, _ := database.Dialect(conf.DBurl)
+	migrator, err := database.NewMigrator(db, dialect, migrationFiles)
+	if err != nil {
+		fmt.Printf("Error when loading migrations:\t %s\n", err)
+		os.Exit(1)
 	}
-	dbQueries := database.New(db)
 	state := &config.State{
-		Db:     dbQueries,
-		Config: conf,
+		Db:       store,
+		Config:   conf,
+		Migrator: migrator,
 	}
-	args := config.CleanArgs(os.Args)
+	args, output := config.CleanArgs(os.Args)
+	state.Output = output
 	_, ok := commands.Map[args[0]]
 	if !ok {
 		fmt.Println("Command does not exist")
 		os.Exit(1)
 	}
 
+	if args[0] != "migrate" {
+		config.RequireCurrentSchema(state)
+	}
+
 	commInput := config.CommandInput{
 		Name: args[0],
 		Args: args,
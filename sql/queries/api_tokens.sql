@@ -0,0 +1,20 @@
+-- name: CreateApiToken :one
+INSERT INTO api_tokens (id, user_id, name, token_hash, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetApiTokensForUser :many
+SELECT * FROM api_tokens WHERE user_id = $1 ORDER BY created_at;
+
+-- name: GetUserByApiToken :one
+SELECT users.*, api_tokens.id AS token_id
+FROM api_tokens
+INNER JOIN users ON users.id = api_tokens.user_id
+WHERE api_tokens.token_hash = sqlc.arg(token_hash)
+  AND (api_tokens.expires_at IS NULL OR api_tokens.expires_at > sqlc.arg(now));
+
+-- name: TouchApiToken :exec
+UPDATE api_tokens SET last_used_at = $2 WHERE id = $1;
+
+-- name: RevokeApiToken :execrows
+DELETE FROM api_tokens WHERE id = $1 AND user_id = $2;
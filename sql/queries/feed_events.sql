@@ -0,0 +1,15 @@
+-- name: CreateFeedEvent :exec
+INSERT INTO feed_events (id, created_at, feed_id, feed_url, kind, detail)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
+
+-- name: GetFeedEvents :many
+SELECT * FROM feed_events
+ORDER BY created_at DESC
+LIMIT $1;
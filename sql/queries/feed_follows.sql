@@ -22,7 +22,17 @@ INNER JOIN users ON users.id = inserted_feed_follow.user_id;
 SELECT
     feed_follows.*,
     feeds.name AS feed_name,
-    users.name AS user_name
+    feeds.url AS feed_url,
+    feeds.site_url AS feed_site_url,
+    users.name AS user_name,
+    (
+        SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id = feed_follows.feed_id
+          AND NOT EXISTS (
+            SELECT 1 FROM post_reads
+            WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+          )
+    ) AS unread_count
 FROM feed_follows
 INNER JOIN users ON users.id=feed_follows.user_id
 INNER JOIN feeds ON feeds.id=feed_follows.feed_id
@@ -35,4 +45,13 @@ USING users, feeds
 WHERE users.id = feed_follows.user_id
   AND feeds.id = feed_follows.feed_id
   AND users.id = $1
-  AND feeds.url = $2;
\ No newline at end of file
+  AND feeds.url = $2;
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_follows.feed_id = sqlc.arg(from_feed_id)
+  AND feed_follows.user_id NOT IN (SELECT kept.user_id FROM feed_follows AS kept WHERE kept.feed_id = sqlc.arg(to_feed_id));
+
+-- name: SetFeedFollowCategory :exec
+UPDATE feed_follows SET category = $3, updated_at = $4
+WHERE user_id = $1 AND feed_id = $2;
@@ -0,0 +1,10 @@
+-- name: GetFeedIcon :one
+SELECT * FROM feed_icons WHERE feed_id = $1;
+
+-- name: UpsertFeedIcon :exec
+INSERT INTO feed_icons (feed_id, mime_type, data, fetched_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (feed_id) DO UPDATE SET
+    mime_type = EXCLUDED.mime_type,
+    data = EXCLUDED.data,
+    fetched_at = EXCLUDED.fetched_at;
@@ -16,11 +16,71 @@ DELETE FROM feeds WHERE user_id IS NULL;
 -- name: GetFeed :one
 SELECT * FROM feeds WHERE url = $1;
 
+-- name: GetFeedByID :one
+SELECT * FROM feeds WHERE id = $1;
+
+-- name: GetAllFeeds :many
+SELECT * FROM feeds ORDER BY name;
+
 -- name: GetFeeds :many
-SELECT feeds.name, feeds.url, users.name AS username FROM feeds INNER JOIN users ON feeds.user_id=users.id;
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.site_url, feeds.user_id, feeds.last_fetched_at, users.name AS username
+FROM feeds INNER JOIN users ON feeds.user_id=users.id;
 
 -- name: MarkFeedFetched :exec
 UPDATE feeds SET last_fetched_at = $2, updated_at = $2 WHERE feeds.id = $1;
 
--- name: GetNextFeedToFetch :one
-SELECT * FROM feeds ORDER BY last_fetched_at NULLS FIRST LIMIT 1;
\ No newline at end of file
+-- name: ClaimFeedsToFetch :many
+UPDATE feeds SET last_fetched_at = sqlc.arg(now), updated_at = sqlc.arg(now), next_fetch_at = sqlc.arg(lease_until)
+WHERE feeds.id IN (
+    SELECT due.id FROM feeds AS due
+    WHERE due.disabled_at IS NULL AND (due.next_fetch_at IS NULL OR due.next_fetch_at <= sqlc.arg(now))
+    ORDER BY due.next_fetch_at NULLS FIRST, due.last_fetched_at NULLS FIRST
+    LIMIT sqlc.arg(max_results)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: SetFeedFormat :exec
+UPDATE feeds SET format = $2 WHERE feeds.id = $1;
+
+-- name: SetFeedCacheHeaders :exec
+UPDATE feeds SET etag = $2, last_modified = $3 WHERE feeds.id = $1;
+
+-- name: SetFeedNextFetch :exec
+UPDATE feeds SET next_fetch_at = $2 WHERE feeds.id = $1;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0, last_error = NULL, last_status = $2, last_success_at = $3
+WHERE feeds.id = $1;
+
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1, last_error = $2, last_status = $3
+WHERE feeds.id = $1
+RETURNING consecutive_failures;
+
+-- name: DisableFeed :exec
+UPDATE feeds SET disabled_at = $2 WHERE feeds.id = $1;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled_at = NULL, consecutive_failures = 0, next_fetch_at = NULL
+WHERE feeds.id = $1;
+
+-- name: GetUnhealthyFeeds :many
+SELECT * FROM feeds
+WHERE consecutive_failures > 0 OR disabled_at IS NOT NULL
+ORDER BY disabled_at NULLS LAST, consecutive_failures DESC, name;
+
+-- name: UpdateFeedUrl :exec
+UPDATE feeds SET url = $2, updated_at = $3 WHERE feeds.id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE feeds.id = $1;
+
+-- name: SetFeedSiteUrl :exec
+UPDATE feeds SET site_url = $2 WHERE feeds.id = $1;
+
+-- name: SetFeedRetention :exec
+UPDATE feeds SET retention_keep_posts = $2, retention_keep_days = $3, updated_at = $4 WHERE feeds.id = $1;
\ No newline at end of file
@@ -0,0 +1,148 @@
+-- name: SetFeverKey :exec
+INSERT INTO fever_keys (user_id, key_hash, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET
+    key_hash = EXCLUDED.key_hash,
+    created_at = EXCLUDED.created_at;
+
+-- name: DeleteFeverKey :execrows
+DELETE FROM fever_keys WHERE user_id = $1;
+
+-- name: GetUserByFeverKey :one
+SELECT users.*
+FROM fever_keys
+INNER JOIN users ON users.id = fever_keys.user_id
+WHERE fever_keys.key_hash = $1;
+
+-- name: GetFeverFeeds :many
+SELECT
+    fever_feeds.id AS fever_id,
+    feeds.id,
+    feeds.name,
+    feeds.url,
+    feeds.site_url,
+    feeds.last_success_at,
+    feed_follows.category,
+    feed_icons.mime_type AS icon_mime_type
+FROM feed_follows
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+INNER JOIN fever_feeds ON fever_feeds.feed_id = feeds.id
+LEFT JOIN feed_icons ON feed_icons.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY fever_feeds.id;
+
+-- name: GetFeverFavicons :many
+SELECT fever_feeds.id AS fever_id, feed_icons.mime_type, feed_icons.data
+FROM feed_follows
+INNER JOIN fever_feeds ON fever_feeds.feed_id = feed_follows.feed_id
+INNER JOIN feed_icons ON feed_icons.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1 AND feed_icons.mime_type <> ''
+ORDER BY fever_feeds.id;
+
+-- name: GetFeverItemsAfter :many
+SELECT
+    fever_items.id AS fever_id,
+    fever_feeds.id AS fever_feed_id,
+    posts.id,
+    posts.created_at,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    feeds.name AS feed_name,
+    post_reads.read_at,
+    saved_posts.created_at AS saved_at
+FROM fever_items
+INNER JOIN posts ON posts.id = fever_items.post_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN fever_feeds ON fever_feeds.feed_id = posts.feed_id
+LEFT JOIN post_reads ON post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+LEFT JOIN saved_posts ON saved_posts.user_id = feed_follows.user_id AND saved_posts.post_id = posts.id
+WHERE feed_follows.user_id = sqlc.arg(user_id) AND fever_items.id > sqlc.arg(since_id)
+ORDER BY fever_items.id
+LIMIT sqlc.arg(max_results);
+
+-- name: GetFeverItemsBefore :many
+SELECT
+    fever_items.id AS fever_id,
+    fever_feeds.id AS fever_feed_id,
+    posts.id,
+    posts.created_at,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    feeds.name AS feed_name,
+    post_reads.read_at,
+    saved_posts.created_at AS saved_at
+FROM fever_items
+INNER JOIN posts ON posts.id = fever_items.post_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN fever_feeds ON fever_feeds.feed_id = posts.feed_id
+LEFT JOIN post_reads ON post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+LEFT JOIN saved_posts ON saved_posts.user_id = feed_follows.user_id AND saved_posts.post_id = posts.id
+WHERE feed_follows.user_id = sqlc.arg(user_id) AND fever_items.id < sqlc.arg(max_id)
+ORDER BY fever_items.id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: GetFeverItem :one
+SELECT
+    fever_items.id AS fever_id,
+    fever_feeds.id AS fever_feed_id,
+    posts.id,
+    posts.created_at,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    feeds.name AS feed_name,
+    post_reads.read_at,
+    saved_posts.created_at AS saved_at
+FROM fever_items
+INNER JOIN posts ON posts.id = fever_items.post_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN fever_feeds ON fever_feeds.feed_id = posts.feed_id
+LEFT JOIN post_reads ON post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+LEFT JOIN saved_posts ON saved_posts.user_id = feed_follows.user_id AND saved_posts.post_id = posts.id
+WHERE feed_follows.user_id = sqlc.arg(user_id) AND fever_items.id = sqlc.arg(id);
+
+-- name: CountFeverItems :one
+SELECT COUNT(*)
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1;
+
+-- name: GetUnreadFeverItemIDs :many
+SELECT fever_items.id
+FROM fever_items
+INNER JOIN posts ON posts.id = fever_items.post_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+  AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+  )
+ORDER BY fever_items.id;
+
+-- name: GetSavedFeverItemIDs :many
+SELECT fever_items.id
+FROM saved_posts
+INNER JOIN fever_items ON fever_items.post_id = saved_posts.post_id
+INNER JOIN posts ON posts.id = saved_posts.post_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = saved_posts.user_id
+WHERE saved_posts.user_id = $1
+ORDER BY fever_items.id;
+
+-- name: MarkFeverItemsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (posts.feed_id = sqlc.narg(feed_id) OR sqlc.narg(feed_id) IS NULL)
+  AND (feed_follows.category = sqlc.narg(category) OR sqlc.narg(category) IS NULL)
+  AND posts.created_at < sqlc.arg(fetched_before)
+ON CONFLICT (user_id, post_id) DO NOTHING;
@@ -1,5 +1,5 @@
--- name: CreatePost :one
-INSERT INTO posts (id,created_at,updated_at,title,url,description,published_at, feed_id)
+-- name: UpsertPost :one
+INSERT INTO posts (id,created_at,updated_at,title,url,description,published_at, feed_id, guid, content_hash)
 VALUES (
     $1,
     $2,
@@ -8,13 +8,163 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10
 )
-RETURNING *;
-
--- name: GetPostsForUser :many
-SELECT posts.* FROM posts 
-INNER JOIN feed_follows ON posts.feed_id=feed_follows.feed_id 
-WHERE feed_follows.user_id=$1 
-ORDER BY posts.published_at DESC NULLS LAST
-LIMIT $2;
\ No newline at end of file
+ON CONFLICT (feed_id, guid) DO UPDATE SET
+    -- posts stored before content_hash existed only get their hash filled in
+    updated_at = CASE WHEN posts.content_hash IS NULL THEN posts.updated_at ELSE EXCLUDED.updated_at END,
+    title = CASE WHEN posts.content_hash IS NULL THEN posts.title ELSE EXCLUDED.title END,
+    url = CASE WHEN posts.content_hash IS NULL THEN posts.url ELSE EXCLUDED.url END,
+    description = CASE WHEN posts.content_hash IS NULL THEN posts.description ELSE EXCLUDED.description END,
+    content_hash = EXCLUDED.content_hash
+WHERE posts.content_hash IS DISTINCT FROM EXCLUDED.content_hash
+RETURNING *, posts.updated_at = $3 AS changed;
+
+-- name: BrowsePostsByFetched :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feed_follows.category,
+    post_reads.read_at
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+LEFT JOIN post_reads ON post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (posts.feed_id = sqlc.narg(feed_id) OR sqlc.narg(feed_id) IS NULL)
+  AND (feed_follows.category = sqlc.narg(category) OR substr(feed_follows.category, 1, length(sqlc.narg(category)) + 1) = sqlc.narg(category) || '/' OR sqlc.narg(category) IS NULL)
+  AND (posts.published_at >= sqlc.narg(since) OR sqlc.narg(since) IS NULL)
+  AND (posts.published_at < sqlc.narg(until) OR sqlc.narg(until) IS NULL)
+  AND (CAST(sqlc.arg(unread_only) AS boolean) = FALSE OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+  ))
+  AND (posts.created_at < sqlc.narg(after_time)
+    OR (posts.created_at = sqlc.narg(after_time) AND posts.id < sqlc.narg(after_id))
+    OR sqlc.narg(after_time) IS NULL)
+ORDER BY posts.created_at DESC, posts.id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: BrowsePostsByPublished :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feed_follows.category,
+    post_reads.read_at
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+LEFT JOIN post_reads ON post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (posts.feed_id = sqlc.narg(feed_id) OR sqlc.narg(feed_id) IS NULL)
+  AND (feed_follows.category = sqlc.narg(category) OR substr(feed_follows.category, 1, length(sqlc.narg(category)) + 1) = sqlc.narg(category) || '/' OR sqlc.narg(category) IS NULL)
+  AND (posts.published_at >= sqlc.narg(since) OR sqlc.narg(since) IS NULL)
+  AND (posts.published_at < sqlc.narg(until) OR sqlc.narg(until) IS NULL)
+  AND (CAST(sqlc.arg(unread_only) AS boolean) = FALSE OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+  ))
+  AND (posts.published_at < sqlc.narg(after_time)
+    OR (posts.published_at = sqlc.narg(after_time) AND posts.id < sqlc.narg(after_id))
+    OR sqlc.narg(after_time) IS NULL)
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: GetPost :one
+SELECT * FROM posts WHERE id = $1;
+
+-- name: SearchPosts :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    ts_rank(post_search.document, query) AS rank,
+    ts_headline(
+        'english',
+        regexp_replace(coalesce(nullif(posts.description, ''), posts.title), '<[^>]*>', ' ', 'g'),
+        query,
+        'StartSel=**, StopSel=**, MaxWords=30, MinWords=10, MaxFragments=2'
+    )::text AS snippet
+FROM post_search
+INNER JOIN posts ON posts.id = post_search.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id,
+    to_tsquery('english', sqlc.arg(query)) query
+WHERE post_search.document @@ query
+  AND (sqlc.arg(all_feeds)::boolean OR posts.feed_id IN (SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = sqlc.arg(user_id)))
+  AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+  AND (sqlc.narg(since)::timestamp IS NULL OR posts.published_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamp IS NULL OR posts.published_at < sqlc.narg(until))
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT sqlc.arg(max_results);
+
+-- name: GetPrunablePosts :many
+SELECT posts.id, posts.title, posts.published_at FROM posts
+WHERE posts.feed_id = sqlc.arg(feed_id)
+  AND (
+    (CAST(sqlc.arg(keep_posts) AS integer) > 0 AND posts.id NOT IN (
+        SELECT newest.id FROM posts newest
+        WHERE newest.feed_id = sqlc.arg(feed_id)
+        ORDER BY coalesce(newest.published_at, newest.created_at) DESC
+        LIMIT sqlc.arg(keep_posts)
+    ))
+    OR coalesce(posts.published_at, posts.created_at) < sqlc.arg(published_before)
+  )
+  AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+  AND NOT (
+    coalesce(posts.published_at, posts.created_at) >= sqlc.arg(unread_since)
+    AND EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+          AND NOT EXISTS (
+            SELECT 1 FROM post_reads
+            WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+          )
+    )
+  )
+ORDER BY coalesce(posts.published_at, posts.created_at);
+
+-- name: GetRecentPublishTimes :many
+-- Items without a date are stored with the fetch time, which is also their
+-- created_at; those say nothing about how often the feed publishes.
+SELECT published_at FROM posts
+WHERE feed_id = $1 AND published_at IS NOT NULL AND published_at <> created_at
+ORDER BY published_at DESC
+LIMIT $2;
+
+-- name: GetKeepPostsCutoff :one
+-- Ranks posts the same way PrunePosts does for keep_posts.
+SELECT published_at, created_at FROM posts
+WHERE feed_id = $1
+ORDER BY coalesce(published_at, created_at) DESC
+LIMIT 1 OFFSET $2;
+
+-- name: PrunePosts :execrows
+DELETE FROM posts
+WHERE posts.feed_id = sqlc.arg(feed_id)
+  AND (
+    (CAST(sqlc.arg(keep_posts) AS integer) > 0 AND posts.id NOT IN (
+        SELECT newest.id FROM posts newest
+        WHERE newest.feed_id = sqlc.arg(feed_id)
+        ORDER BY coalesce(newest.published_at, newest.created_at) DESC
+        LIMIT sqlc.arg(keep_posts)
+    ))
+    OR coalesce(posts.published_at, posts.created_at) < sqlc.arg(published_before)
+  )
+  AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+  AND NOT (
+    coalesce(posts.published_at, posts.created_at) >= sqlc.arg(unread_since)
+    AND EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+          AND NOT EXISTS (
+            SELECT 1 FROM post_reads
+            WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+          )
+    )
+  );
+
+-- name: MovePosts :exec
+UPDATE posts SET feed_id = sqlc.arg(to_feed_id)
+WHERE posts.feed_id = sqlc.arg(from_feed_id)
+  AND posts.guid NOT IN (SELECT kept.guid FROM posts AS kept WHERE kept.feed_id = sqlc.arg(to_feed_id));
\ No newline at end of file
@@ -0,0 +1,51 @@
+-- name: SavePost :exec
+INSERT INTO saved_posts (user_id, post_id, created_at, note)
+VALUES (sqlc.arg(user_id), sqlc.arg(post_id), sqlc.arg(created_at), sqlc.narg(note))
+ON CONFLICT (user_id, post_id) DO UPDATE SET
+    note = CASE
+        WHEN CAST(sqlc.arg(clear_note) AS boolean) THEN NULL
+        ELSE COALESCE(EXCLUDED.note, saved_posts.note)
+    END;
+
+-- name: MergeSavedPostNotes :exec
+-- Appends the note of a star on a post of from_feed_id to the user's star on
+-- the same post of to_feed_id, before MoveSavedPosts drops the former.
+UPDATE saved_posts SET note = CASE
+    WHEN saved_posts.note IS NULL OR saved_posts.note = source_saved.note THEN source_saved.note
+    ELSE saved_posts.note || '; ' || source_saved.note
+  END
+FROM saved_posts source_saved, posts source, posts target
+WHERE target.id = saved_posts.post_id
+  AND target.feed_id = sqlc.arg(to_feed_id)
+  AND source.feed_id = sqlc.arg(from_feed_id)
+  AND source.guid = target.guid
+  AND source_saved.post_id = source.id
+  AND source_saved.user_id = saved_posts.user_id
+  AND source_saved.note IS NOT NULL;
+
+-- name: MoveSavedPosts :exec
+UPDATE saved_posts SET post_id = target.id
+FROM posts source, posts target
+WHERE source.id = saved_posts.post_id
+  AND source.feed_id = sqlc.arg(from_feed_id)
+  AND target.feed_id = sqlc.arg(to_feed_id)
+  AND target.guid = source.guid
+  AND NOT EXISTS (
+    SELECT 1 FROM saved_posts other
+    WHERE other.user_id = saved_posts.user_id AND other.post_id = target.id
+  );
+
+-- name: UnsavePost :execrows
+DELETE FROM saved_posts WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetSavedPostsForUser :many
+SELECT
+    posts.*,
+    saved_posts.note,
+    saved_posts.created_at AS saved_at,
+    feeds.name AS feed_name
+FROM saved_posts
+INNER JOIN posts ON posts.id = saved_posts.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE saved_posts.user_id = $1
+ORDER BY saved_posts.created_at DESC;
\ No newline at end of file
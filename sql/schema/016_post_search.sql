@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE post_search (
+  post_id UUID PRIMARY KEY,
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+  document tsvector NOT NULL
+);
+CREATE INDEX post_search_document_idx ON post_search USING GIN (document);
+
+-- +goose StatementBegin
+CREATE FUNCTION post_search_document(title TEXT, description TEXT) RETURNS tsvector AS $$
+  SELECT setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+         setweight(to_tsvector('english', regexp_replace(coalesce(description, ''), '<[^>]*>', ' ', 'g')), 'B');
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION post_search_refresh() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO post_search (post_id, document)
+  VALUES (NEW.id, post_search_document(NEW.title, NEW.description))
+  ON CONFLICT (post_id) DO UPDATE SET document = EXCLUDED.document;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER posts_search_refresh AFTER INSERT OR UPDATE OF title, description ON posts
+FOR EACH ROW EXECUTE FUNCTION post_search_refresh();
+
+INSERT INTO post_search (post_id, document)
+SELECT id, post_search_document(title, description) FROM posts;
+
+-- +goose Down
+DROP TRIGGER posts_search_refresh ON posts;
+DROP FUNCTION post_search_refresh();
+DROP FUNCTION post_search_document(TEXT, TEXT);
+DROP TABLE post_search;
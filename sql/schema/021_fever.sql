@@ -0,0 +1,68 @@
+-- +goose Up
+CREATE TABLE fever_keys (
+  user_id UUID PRIMARY KEY,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  key_hash TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMP NOT NULL
+);
+
+-- Fever identifies feeds and items by integers, item ids have to grow in
+-- the order posts arrive for since_id paging.
+CREATE TABLE fever_feeds (
+  id SERIAL PRIMARY KEY,
+  feed_id UUID NOT NULL UNIQUE,
+  FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE TABLE fever_items (
+  id BIGSERIAL PRIMARY KEY,
+  post_id UUID NOT NULL UNIQUE,
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+INSERT INTO fever_feeds (feed_id) SELECT id FROM feeds ORDER BY created_at, id;
+INSERT INTO fever_items (post_id) SELECT id FROM posts ORDER BY created_at, id;
+
+-- +goose StatementBegin
+CREATE FUNCTION fever_feeds_insert() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO fever_feeds (feed_id) VALUES (NEW.id) ON CONFLICT (feed_id) DO NOTHING;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION fever_items_insert() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO fever_items (post_id) VALUES (NEW.id) ON CONFLICT (post_id) DO NOTHING;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER feeds_fever_insert AFTER INSERT ON feeds
+FOR EACH ROW EXECUTE FUNCTION fever_feeds_insert();
+
+CREATE TRIGGER posts_fever_insert AFTER INSERT ON posts
+FOR EACH ROW EXECUTE FUNCTION fever_items_insert();
+
+-- An empty mime_type records a failed lookup, so it is not retried on every
+-- fetch.
+CREATE TABLE feed_icons (
+  feed_id UUID PRIMARY KEY,
+  FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+  mime_type TEXT NOT NULL,
+  data BYTEA NOT NULL,
+  fetched_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE feed_icons;
+DROP TRIGGER posts_fever_insert ON posts;
+DROP TRIGGER feeds_fever_insert ON feeds;
+DROP FUNCTION fever_items_insert();
+DROP FUNCTION fever_feeds_insert();
+DROP TABLE fever_items;
+DROP TABLE fever_feeds;
+DROP TABLE fever_keys;
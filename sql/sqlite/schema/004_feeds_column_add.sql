@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE feeds ADD last_fetched_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN last_fetched_at;
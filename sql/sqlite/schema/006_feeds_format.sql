@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE feeds ADD format TEXT;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN format;
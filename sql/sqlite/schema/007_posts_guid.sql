@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE posts_new (
+  id TEXT PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  title TEXT NOT NULL,
+  url TEXT NOT NULL,
+  description TEXT,
+  published_at TIMESTAMP,
+  feed_id TEXT NOT NULL,
+  guid TEXT NOT NULL,
+  content_hash TEXT,
+  FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+  CONSTRAINT posts_feed_id_guid_key UNIQUE (feed_id, guid)
+);
+INSERT INTO posts_new (id, created_at, updated_at, title, url, description, published_at, feed_id, guid)
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, url FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_new RENAME TO posts;
+
+-- +goose Down
+CREATE TABLE posts_old (
+  id TEXT PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  title TEXT NOT NULL,
+  url TEXT NOT NULL UNIQUE,
+  description TEXT,
+  published_at TIMESTAMP,
+  feed_id TEXT NOT NULL,
+  FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+INSERT OR IGNORE INTO posts_old (id, created_at, updated_at, title, url, description, published_at, feed_id)
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id FROM posts
+ORDER BY created_at;
+DROP TABLE posts;
+ALTER TABLE posts_old RENAME TO posts;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE feed_events (
+  id TEXT PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  feed_id TEXT,
+  feed_url TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  detail TEXT,
+  FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE feed_events;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE saved_posts (
+  user_id TEXT NOT NULL,
+  post_id TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  note TEXT,
+  PRIMARY KEY (user_id, post_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE saved_posts;
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE VIRTUAL TABLE post_search USING fts4(post_id, title, description, notindexed=post_id, tokenize=porter);
+
+INSERT INTO post_search (docid, post_id, title, description)
+SELECT rowid, id, title, coalesce(description, '') FROM posts;
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_insert AFTER INSERT ON posts BEGIN
+  INSERT INTO post_search (docid, post_id, title, description)
+  VALUES (NEW.rowid, NEW.id, NEW.title, coalesce(NEW.description, ''));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_update AFTER UPDATE OF title, description ON posts BEGIN
+  DELETE FROM post_search WHERE docid = OLD.rowid;
+  INSERT INTO post_search (docid, post_id, title, description)
+  VALUES (NEW.rowid, NEW.id, NEW.title, coalesce(NEW.description, ''));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_delete AFTER DELETE ON posts BEGIN
+  DELETE FROM post_search WHERE docid = OLD.rowid;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER posts_search_delete;
+DROP TRIGGER posts_search_update;
+DROP TRIGGER posts_search_insert;
+DROP TABLE post_search;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds ADD retention_keep_posts INTEGER;
+ALTER TABLE feeds ADD retention_keep_days INTEGER;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN retention_keep_days;
+ALTER TABLE feeds DROP COLUMN retention_keep_posts;
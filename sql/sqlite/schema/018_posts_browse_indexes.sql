@@ -0,0 +1,8 @@
+-- +goose Up
+UPDATE posts SET published_at = created_at WHERE published_at IS NULL;
+CREATE INDEX posts_feed_published_idx ON posts (feed_id, published_at DESC, id DESC);
+CREATE INDEX posts_feed_created_idx ON posts (feed_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX posts_feed_created_idx;
+DROP INDEX posts_feed_published_idx;
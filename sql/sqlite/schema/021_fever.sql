@@ -0,0 +1,55 @@
+-- +goose Up
+CREATE TABLE fever_keys (
+  user_id TEXT PRIMARY KEY,
+  key_hash TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMP NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Fever identifies feeds and items by integers, item ids have to grow in
+-- the order posts arrive for since_id paging. AUTOINCREMENT keeps ids of
+-- deleted rows from being reused.
+CREATE TABLE fever_feeds (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  feed_id TEXT NOT NULL UNIQUE,
+  FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE TABLE fever_items (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  post_id TEXT NOT NULL UNIQUE,
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+INSERT INTO fever_feeds (feed_id) SELECT id FROM feeds ORDER BY created_at, id;
+INSERT INTO fever_items (post_id) SELECT id FROM posts ORDER BY created_at, id;
+
+-- +goose StatementBegin
+CREATE TRIGGER feeds_fever_insert AFTER INSERT ON feeds BEGIN
+  INSERT OR IGNORE INTO fever_feeds (feed_id) VALUES (NEW.id);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fever_insert AFTER INSERT ON posts BEGIN
+  INSERT OR IGNORE INTO fever_items (post_id) VALUES (NEW.id);
+END;
+-- +goose StatementEnd
+
+-- An empty mime_type records a failed lookup, so it is not retried on every
+-- fetch.
+CREATE TABLE feed_icons (
+  feed_id TEXT PRIMARY KEY,
+  mime_type TEXT NOT NULL,
+  data BLOB NOT NULL,
+  fetched_at TIMESTAMP NOT NULL,
+  FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_icons;
+DROP TRIGGER posts_fever_insert;
+DROP TRIGGER feeds_fever_insert;
+DROP TABLE fever_items;
+DROP TABLE fever_feeds;
+DROP TABLE fever_keys;